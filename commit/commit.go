@@ -284,7 +284,13 @@ func buildCommitTree(
 			node.CfgChildren = append(node.CfgChildren, child)
 		}
 	}
-	for _, n := range node.Children() {
+	children := node.Children
+	if skipUnchanged {
+		// Identical subtrees can never contribute to the commit
+		// tree, so avoid walking them.
+		children = node.ChangedChildren
+	}
+	for _, n := range children() {
 		child = buildCommitTree(ctx, node, n, skipUnchanged, skipDeleted)
 		if child == nil {
 			continue
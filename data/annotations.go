@@ -0,0 +1,101 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"sort"
+)
+
+// Annotations are RFC 7952 metadata attached to a node, eg who last
+// modified it. Names are module qualified ("module:annotation"), matching
+// their RFC 7951 JSON encoding; the values are opaque strings.
+
+func copyAnnotations(in map[string]string) map[string]string {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(in))
+	for k, v := range in {
+		out[k] = v
+	}
+	return out
+}
+
+// SortedAnnotationNames returns the names of the annotations in a
+// consistent order, for encoding them.
+func SortedAnnotationNames(in map[string]string) []string {
+	names := make([]string, 0, len(in))
+	for k := range in {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (n *Node) Annotation(name string) (string, bool) {
+	v, ok := n.annotations[name]
+	return v, ok
+}
+
+// Annotations returns a copy of all annotations on the node, or nil if
+// there are none.
+func (n *Node) Annotations() map[string]string {
+	if n == nil {
+		return nil
+	}
+	return copyAnnotations(n.annotations)
+}
+
+func (n *Node) SetAnnotation(name, value string) {
+	n.recordState(func() {
+		if n.annotations == nil {
+			n.annotations = make(map[string]string)
+		}
+		n.annotations[name] = value
+	})
+}
+
+func (n *Node) DeleteAnnotation(name string) {
+	if _, ok := n.annotations[name]; !ok {
+		return
+	}
+	n.recordState(func() {
+		delete(n.annotations, name)
+	})
+}
+
+func (p *Persistent) Annotation(name string) (string, bool) {
+	v, ok := p.annotations[name]
+	return v, ok
+}
+
+func (p *Persistent) Annotations() map[string]string {
+	if p == nil {
+		return nil
+	}
+	return copyAnnotations(p.annotations)
+}
+
+// SetAnnotation returns a copy of p with the annotation set. The
+// annotation map of a Persistent is never modified once shared.
+func (p *Persistent) SetAnnotation(name, value string) *Persistent {
+	out := p.clone()
+	out.annotations = copyAnnotations(p.annotations)
+	if out.annotations == nil {
+		out.annotations = make(map[string]string)
+	}
+	out.annotations[name] = value
+	return out
+}
+
+func (p *Persistent) DeleteAnnotation(name string) *Persistent {
+	if _, ok := p.annotations[name]; !ok {
+		return p
+	}
+	out := p.clone()
+	out.annotations = copyAnnotations(p.annotations)
+	delete(out.annotations, name)
+	return out
+}
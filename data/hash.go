@@ -0,0 +1,131 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync/atomic"
+)
+
+// Digest is the content hash of a Node and all of its descendants.
+type Digest [sha256.Size]byte
+
+type childDigest struct {
+	name string
+	idx  uint64
+	sum  Digest
+}
+
+// Hash returns the Merkle hash of the subtree rooted at n. It covers the
+// name, comment, annotations and flags of every node and the relative
+// (index) order of children, so two subtrees with the same Hash hold the
+// same configuration in the same order.
+//
+// Hashes are only cached by Persistent trees, which can't change and so
+// can be shared freely. For a Node thawed from a Persistent tree only the
+// parts modified or read since it was thawed are walked; for any other
+// Node the whole subtree is walked on every call.
+func (n *Node) Hash() Digest {
+	if n == nil {
+		return Digest{}
+	}
+	if n.frozen != nil && atomic.LoadUint32(&n.thawed) == 0 {
+		return n.frozen.Hash()
+	}
+	n.thaw()
+	children := make([]childDigest, 0, len(n.children))
+	for _, ch := range n.children {
+		children = append(children, childDigest{ch.name, ch.idx, ch.Hash()})
+	}
+	return computeHash(n.name, n.comment, n.flags, n.annotations, children)
+}
+
+// Hash returns the Merkle hash of the subtree rooted at p, as for
+// Node.Hash. It is computed at most once for each Persistent node.
+func (p *Persistent) Hash() Digest {
+	if p == nil {
+		return Digest{}
+	}
+	if sum, ok := p.hash.Load().(Digest); ok {
+		return sum
+	}
+	children := make([]childDigest, 0, p.nchildren)
+	p.children.each(func(name string, ch *Persistent) {
+		children = append(children, childDigest{name, ch.idx, ch.Hash()})
+	})
+	sum := computeHash(p.name, p.comment, p.flags, p.annotations, children)
+	p.hash.Store(sum)
+	return sum
+}
+
+// Equal reports whether n and other hold identical subtrees. Unless
+// SameVersion holds, this walks both subtrees.
+func (n *Node) Equal(other *Node) bool {
+	if n.SameVersion(other) {
+		return true
+	}
+	if n == nil || other == nil {
+		return false
+	}
+	return n.Hash() == other.Hash()
+}
+
+// SameVersion reports whether n and other are known to hold identical
+// subtrees without walking either of them; that is when they are the
+// same node, or are both unmodified copies of Persistent subtrees with
+// the same hash. A false result does not mean the subtrees differ.
+func (n *Node) SameVersion(other *Node) bool {
+	if n == other {
+		return true
+	}
+	if n == nil || other == nil {
+		return false
+	}
+	if n.frozen == nil || atomic.LoadUint32(&n.thawed) != 0 ||
+		other.frozen == nil || atomic.LoadUint32(&other.thawed) != 0 {
+		return false
+	}
+	return n.frozen == other.frozen || n.frozen.Hash() == other.frozen.Hash()
+}
+
+func computeHash(
+	name, comment string,
+	flags uint32,
+	annotations map[string]string,
+	children []childDigest,
+) Digest {
+	var buf [8]byte
+	h := sha256.New()
+	writeString := func(s string) {
+		binary.BigEndian.PutUint64(buf[:], uint64(len(s)))
+		h.Write(buf[:])
+		h.Write([]byte(s))
+	}
+
+	writeString(name)
+	writeString(comment)
+	binary.BigEndian.PutUint32(buf[:4], flags)
+	h.Write(buf[:4])
+	for _, name := range SortedAnnotationNames(annotations) {
+		writeString(name)
+		writeString(annotations[name])
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].idx == children[j].idx {
+			return children[i].name < children[j].name
+		}
+		return children[i].idx < children[j].idx
+	})
+	for _, ch := range children {
+		h.Write(ch.sum[:])
+	}
+
+	var out Digest
+	copy(out[:], h.Sum(nil))
+	return out
+}
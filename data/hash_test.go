@@ -0,0 +1,127 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"testing"
+)
+
+func TestHashEqualTrees(t *testing.T) {
+	a, b := createBaseTree(), createBaseTree()
+	if a.Hash() != b.Hash() {
+		t.Fatal("identical trees have different hashes")
+	}
+	if !a.Equal(b) {
+		t.Fatal("identical trees not equal")
+	}
+}
+
+func TestHashInvalidation(t *testing.T) {
+	type mutation struct {
+		name string
+		fn   func(*Node)
+	}
+	mutations := []mutation{
+		{"AddChild", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").AddChild(New("new"))
+		}},
+		{"DeleteChild", func(n *Node) {
+			n.Child("Test3").DeleteChild("TestCh2")
+		}},
+		{"ClearChildren", func(n *Node) {
+			n.Child("Test3").ClearChildren()
+		}},
+		{"SetComment", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").SetComment("comment")
+		}},
+		{"SetIndex", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").SetIndex(100)
+		}},
+		{"MarkDeleted", func(n *Node) {
+			n.Child("Test3").MarkDeleted(DontClearChildFlags)
+		}},
+		{"MarkOpaque", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").MarkOpaque()
+		}},
+		{"MarkDefault", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").MarkDefault()
+		}},
+	}
+	for _, m := range mutations {
+		orig := createBaseTree()
+		tree := createBaseTree()
+		before := tree.Hash()
+		unchanged := tree.Child("Test4").Hash()
+		m.fn(tree)
+		if tree.Hash() == before {
+			t.Errorf("%s: hash not invalidated", m.name)
+		}
+		if tree.Child("Test4").Hash() != unchanged {
+			t.Errorf("%s: unrelated subtree hash changed", m.name)
+		}
+		if tree.Equal(orig) {
+			t.Errorf("%s: modified tree equal to original", m.name)
+		}
+	}
+}
+
+func TestHashRestored(t *testing.T) {
+	tree := createBaseTree()
+	before := tree.Hash()
+	ch := tree.Child("Test3")
+	ch.MarkOpaque()
+	if tree.Hash() == before {
+		t.Fatal("hash not invalidated")
+	}
+	ch.ClearOpaque()
+	if tree.Hash() != before {
+		t.Fatal("hash not restored")
+	}
+}
+
+func TestHashPersistent(t *testing.T) {
+	tree := createBaseTree()
+	p := Freeze(tree)
+	if p.Hash() != tree.Hash() {
+		t.Fatal("persistent hash differs from node hash")
+	}
+	if p.Thaw().Hash() != tree.Hash() {
+		t.Fatal("thawed hash differs from node hash")
+	}
+	q := p.Set([]string{"Test3", "TestCh2", "new"})
+	if q.Hash() == p.Hash() {
+		t.Fatal("modified persistent tree has the same hash")
+	}
+	if q.Child("Test4").Hash() != p.Child("Test4").Hash() {
+		t.Fatal("shared subtree hash changed")
+	}
+}
+
+func TestHashSharedSubtree(t *testing.T) {
+	p := Freeze(createBaseTree())
+	a, b := p.Thaw(), p.Thaw()
+	if !a.SameVersion(b) {
+		t.Fatal("copies of the same version differ")
+	}
+	before := b.Hash()
+	a.Child("Test3").Child("TestCh2").SetComment("comment")
+	if a.SameVersion(b) || a.Equal(b) {
+		t.Fatal("modified copy still equal")
+	}
+	if b.Hash() != before || p.Thaw().Hash() != before {
+		t.Fatal("modifying one copy changed the hash of another")
+	}
+
+	// A subtree shared by two mutable trees is hashed afresh in each.
+	shared := New("shared")
+	x, y := New("root"), New("root")
+	x.AddChild(shared)
+	y.AddChild(shared)
+	yhash := y.Hash()
+	shared.SetComment("comment")
+	if y.Hash() == yhash || x.Hash() != y.Hash() {
+		t.Fatal("stale hash for shared subtree")
+	}
+}
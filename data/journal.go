@@ -0,0 +1,461 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"errors"
+)
+
+// A Journal records every modification made to a tree of Nodes, so that
+// changes can be undone and redone, or rolled back to a savepoint.
+//
+// The journal is attached to the root of the tree with SetJournal and
+// covers all nodes reachable from it through their parent links. Nodes
+// that have not yet been added to the tree are not journaled; adding them
+// is. Modifications are grouped into changes with Begin and End, each
+// change being undone or redone as a unit. A modification made outside
+// of Begin/End is a change of its own.
+//
+// At most limit changes are kept for undo; the oldest are discarded, and
+// can't be rolled back, once there are more.
+//
+// A Journal, like the Node tree, is not safe for concurrent use.
+type Journal struct {
+	undo  []*journalChange
+	redo  []*journalChange
+	open  *journalChange
+	depth int
+	seq   uint64
+	limit int
+	//dropped is the seq of the newest change discarded due to the limit
+	dropped uint64
+}
+
+// DefaultJournalLimit is the number of changes a new Journal keeps.
+const DefaultJournalLimit = 1000
+
+// Savepoint identifies the state of the tree at the time it was taken:
+// the change, which may still be open, and the entries in it so far.
+type Savepoint struct {
+	seq     uint64
+	entries int
+	last    journalEntry
+}
+
+var ErrSavepointInvalid = errors.New("savepoint is no longer in the journal")
+
+type journalChange struct {
+	seq     uint64
+	entries []journalEntry
+}
+
+type journalEntry interface {
+	undo()
+	redo()
+}
+
+func NewJournal() *Journal {
+	return &Journal{limit: DefaultJournalLimit}
+}
+
+// SetLimit sets the number of changes kept for undo, discarding the
+// oldest if there are already more. A limit of 0 keeps every change.
+func (j *Journal) SetLimit(limit int) {
+	j.limit = limit
+	j.trim()
+}
+
+func (j *Journal) trim() {
+	if j.limit <= 0 || len(j.undo) <= j.limit {
+		return
+	}
+	drop := len(j.undo) - j.limit
+	j.dropped = j.undo[drop-1].seq
+	for i := 0; i < drop; i++ {
+		j.undo[i] = nil
+	}
+	j.undo = j.undo[drop:]
+}
+
+// Begin starts a change; calls may be nested, the change is complete
+// when the outermost Begin is matched by End. Both are no-ops on a nil
+// Journal to allow callers to use them unconditionally.
+func (j *Journal) Begin() {
+	if j == nil {
+		return
+	}
+	if j.depth == 0 {
+		j.seq++
+		j.open = &journalChange{seq: j.seq}
+	}
+	j.depth++
+}
+
+func (j *Journal) End() {
+	if j == nil || j.depth == 0 {
+		return
+	}
+	j.depth--
+	if j.depth > 0 {
+		return
+	}
+	c := j.open
+	j.open = nil
+	if len(c.entries) > 0 {
+		j.push(c)
+	}
+	c.complete()
+}
+
+func (j *Journal) push(c *journalChange) {
+	j.undo = append(j.undo, c)
+	j.redo = nil
+	j.trim()
+}
+
+func (j *Journal) record(e journalEntry) {
+	if j.open != nil {
+		j.open.entries = append(j.open.entries, e)
+		return
+	}
+	j.seq++
+	j.push(&journalChange{seq: j.seq, entries: []journalEntry{e}})
+}
+
+// notifyEntry is a notification added to a change with Notify
+type notifyEntry struct {
+	done, undone func()
+	// sent is set once done has been called, until undone is
+	sent bool
+}
+
+func (e *notifyEntry) undo() {
+	if e.sent {
+		e.sent = false
+		e.undone()
+	}
+}
+
+func (e *notifyEntry) redo() {
+	e.sent = true
+	e.done()
+}
+
+// Notify adds a notification to the current change: done is called
+// once the change is complete, and each time it is redone; undone each
+// time it is undone or rolled back. Neither is called if the part of
+// an open change made before Notify is rolled back. On a nil Journal,
+// or outside a change, done is called straight away.
+func (j *Journal) Notify(done, undone func()) {
+	e := &notifyEntry{done: done, undone: undone}
+	if j == nil {
+		done()
+		return
+	}
+	j.record(e)
+	if j.open == nil {
+		e.redo()
+	}
+}
+
+// complete sends the change's notifications, in the order added
+func (c *journalChange) complete() {
+	for _, e := range c.entries {
+		if n, ok := e.(*notifyEntry); ok {
+			n.redo()
+		}
+	}
+}
+
+func (j *Journal) CanUndo() bool {
+	return j != nil && len(j.undo) > 0
+}
+
+func (j *Journal) CanRedo() bool {
+	return j != nil && len(j.redo) > 0
+}
+
+func (c *journalChange) revert() {
+	c.truncate(0)
+}
+
+// truncate reverts, and discards, all but the first n entries
+func (c *journalChange) truncate(n int) {
+	for i := len(c.entries) - 1; i >= n; i-- {
+		c.entries[i].undo()
+	}
+	c.entries = c.entries[:n]
+}
+
+func (c *journalChange) replay() {
+	for _, e := range c.entries {
+		e.redo()
+	}
+}
+
+// Undo reverts the most recent change, returning false if there is
+// nothing to undo.
+func (j *Journal) Undo() bool {
+	if !j.CanUndo() {
+		return false
+	}
+	c := j.undo[len(j.undo)-1]
+	j.undo = j.undo[:len(j.undo)-1]
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		c.entries[i].undo()
+	}
+	j.redo = append(j.redo, c)
+	return true
+}
+
+// Redo reapplies the most recently undone change. Making any new change
+// discards the changes available to redo.
+func (j *Journal) Redo() bool {
+	if !j.CanRedo() {
+		return false
+	}
+	c := j.redo[len(j.redo)-1]
+	j.redo = j.redo[:len(j.redo)-1]
+	c.replay()
+	j.undo = append(j.undo, c)
+	return true
+}
+
+func (c *journalChange) savepoint() Savepoint {
+	sp := Savepoint{seq: c.seq, entries: len(c.entries)}
+	if sp.entries > 0 {
+		sp.last = c.entries[sp.entries-1]
+	}
+	return sp
+}
+
+// Savepoint may be taken within a change, in which case rolling back to
+// it only reverts the part of the change made after it.
+func (j *Journal) Savepoint() Savepoint {
+	switch {
+	case j == nil:
+		return Savepoint{}
+	case j.open != nil && len(j.open.entries) > 0:
+		return j.open.savepoint()
+	case len(j.undo) == 0:
+		return Savepoint{seq: j.dropped}
+	}
+	return j.undo[len(j.undo)-1].savepoint()
+}
+
+// RollbackTo reverts, and discards, every change made since sp was
+// taken, including those in a change that is still open. The open
+// change remains open. ErrSavepointInvalid is returned if the state sp
+// refers to has itself been undone or discarded.
+func (j *Journal) RollbackTo(sp Savepoint) error {
+	if j == nil {
+		if sp.seq != 0 {
+			return ErrSavepointInvalid
+		}
+		return nil
+	}
+	if !j.valid(sp) {
+		return ErrSavepointInvalid
+	}
+	if j.open != nil {
+		keep := 0
+		if j.open.seq == sp.seq {
+			keep = sp.entries
+		}
+		j.open.truncate(keep)
+	}
+	for len(j.undo) > 0 {
+		c := j.undo[len(j.undo)-1]
+		if c.seq < sp.seq {
+			break
+		}
+		if c.seq == sp.seq {
+			c.truncate(sp.entries)
+			break
+		}
+		j.undo = j.undo[:len(j.undo)-1]
+		c.revert()
+	}
+	j.redo = nil
+	return nil
+}
+
+func (j *Journal) valid(sp Savepoint) bool {
+	if sp.seq == j.dropped && sp.entries == 0 {
+		return true
+	}
+	if sp.seq <= j.dropped {
+		return false
+	}
+	if j.open != nil && j.open.seq == sp.seq {
+		return j.open.contains(sp)
+	}
+	for i := len(j.undo) - 1; i >= 0; i-- {
+		if j.undo[i].seq == sp.seq {
+			return j.undo[i].contains(sp)
+		}
+		if j.undo[i].seq < sp.seq {
+			break
+		}
+	}
+	return false
+}
+
+func (c *journalChange) contains(sp Savepoint) bool {
+	if sp.entries == 0 {
+		return true
+	}
+	return len(c.entries) >= sp.entries && c.entries[sp.entries-1] == sp.last
+}
+
+// SetJournal attaches j to the tree rooted at n, nil stops journaling.
+func (n *Node) SetJournal(j *Journal) {
+	n.journal = j
+}
+
+// Journal returns the journal recording changes to n, if any.
+func (n *Node) Journal() *Journal {
+	for ; n != nil; n = n.parent {
+		if n.journal != nil {
+			return n.journal
+		}
+	}
+	return nil
+}
+
+type nodeState struct {
+	comment     string
+	flags       uint32
+	annotations map[string]string
+	idx         uint64
+	nxtChildIdx uint64
+}
+
+func (n *Node) state() nodeState {
+	return nodeState{
+		comment:     n.comment,
+		flags:       n.flags,
+		annotations: copyAnnotations(n.annotations),
+		idx:         n.idx,
+		nxtChildIdx: n.nxtChildIdx,
+	}
+}
+
+func (n *Node) setState(s nodeState) {
+	n.modify()
+	n.comment = s.comment
+	n.flags = s.flags
+	n.annotations = copyAnnotations(s.annotations)
+	n.nxtChildIdx = s.nxtChildIdx
+	n.idx = s.idx
+}
+
+// stateEntry records a change to the node's own attributes
+type stateEntry struct {
+	node          *Node
+	before, after nodeState
+}
+
+func (e *stateEntry) undo() { e.node.setState(e.before) }
+func (e *stateEntry) redo() { e.node.setState(e.after) }
+
+// recordState runs fn, which may only modify n's own attributes, and
+// journals the result.
+func (n *Node) recordState(fn func()) {
+	n.modify()
+	j := n.Journal()
+	if j == nil {
+		fn()
+		return
+	}
+	before := n.state()
+	fn()
+	j.record(&stateEntry{node: n, before: before, after: n.state()})
+}
+
+// childEntry records the replacement (or addition, or removal) of a
+// single child.
+type childEntry struct {
+	parent       *Node
+	name         string
+	old, new     *Node
+	before       nodeState
+	after        nodeState
+	childIdxPrev uint64
+	childIdx     uint64
+}
+
+func (e *childEntry) setChild(ch, displaced *Node, idx uint64) {
+	e.parent.modify()
+	if displaced != nil && displaced != ch && displaced.parent == e.parent {
+		displaced.parent = nil
+	}
+	if ch == nil {
+		delete(e.parent.children, e.name)
+	} else {
+		ch.idx = idx
+		ch.parent = e.parent
+		e.parent.children[e.name] = ch
+	}
+}
+
+func (e *childEntry) undo() {
+	e.setChild(e.old, e.new, e.childIdxPrev)
+	e.parent.setState(e.before)
+}
+
+func (e *childEntry) redo() {
+	e.setChild(e.new, e.old, e.childIdx)
+	e.parent.setState(e.after)
+}
+
+// recordChild runs fn, which may only replace n's child called name,
+// and journals the result.
+func (n *Node) recordChild(name string, fn func()) {
+	n.modify()
+	j := n.Journal()
+	if j == nil {
+		fn()
+		return
+	}
+	e := &childEntry{
+		parent: n,
+		name:   name,
+		old:    n.children[name],
+		before: n.state(),
+	}
+	if e.old != nil {
+		e.childIdxPrev = e.old.idx
+	}
+	fn()
+	e.new = n.children[name]
+	e.after = n.state()
+	if e.new != nil {
+		e.childIdx = e.new.idx
+	}
+	j.record(e)
+}
+
+// childrenEntry records the replacement of all children at once
+type childrenEntry struct {
+	parent   *Node
+	old, new map[string]*Node
+}
+
+func (e *childrenEntry) setChildren(children, displaced map[string]*Node) {
+	for _, ch := range displaced {
+		if ch.parent == e.parent {
+			ch.parent = nil
+		}
+	}
+	for _, ch := range children {
+		ch.parent = e.parent
+	}
+	e.parent.modify()
+	e.parent.children = children
+}
+
+func (e *childrenEntry) undo() { e.setChildren(e.old, e.new) }
+func (e *childrenEntry) redo() { e.setChildren(e.new, e.old) }
@@ -0,0 +1,301 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newJournaledTree() (*Node, *Journal) {
+	root := createBaseTree()
+	j := NewJournal()
+	root.SetJournal(j)
+	return root, j
+}
+
+func TestJournalUndoRedo(t *testing.T) {
+	type mutation struct {
+		name string
+		fn   func(*Node)
+	}
+	mutations := []mutation{
+		{"AddChild", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").AddChild(New("new"))
+		}},
+		{"ReplaceChild", func(n *Node) {
+			n.Child("Test3").AddChild(New("TestCh2"))
+		}},
+		{"DeleteChild", func(n *Node) {
+			n.Child("Test3").DeleteChild("TestCh2")
+		}},
+		{"ClearChildren", func(n *Node) {
+			n.Child("Test3").ClearChildren()
+		}},
+		{"SetComment", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").SetComment("comment")
+		}},
+		{"SetIndex", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").SetIndex(100)
+		}},
+		{"SetAnnotation", func(n *Node) {
+			n.Child("Test3").SetAnnotation("test:note", "value")
+		}},
+		{"MarkDeleted", func(n *Node) {
+			n.Child("Test3").MarkDeleted(ClearChildFlags)
+		}},
+		{"MarkOpaque", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").MarkOpaque()
+		}},
+		{"MarkDefault", func(n *Node) {
+			n.Child("Test3").Child("TestCh2").MarkDefault()
+		}},
+	}
+	for _, m := range mutations {
+		tree, j := newJournaledTree()
+		before := tree.Hash()
+		m.fn(tree)
+		after := tree.Hash()
+		if after == before {
+			t.Fatalf("%s: mutation had no effect", m.name)
+		}
+		if !j.Undo() {
+			t.Fatalf("%s: nothing to undo", m.name)
+		}
+		if tree.Hash() != before {
+			t.Errorf("%s: undo did not restore tree", m.name)
+		}
+		if !j.Redo() {
+			t.Fatalf("%s: nothing to redo", m.name)
+		}
+		if tree.Hash() != after {
+			t.Errorf("%s: redo did not reapply change", m.name)
+		}
+	}
+}
+
+func TestJournalGroupsChanges(t *testing.T) {
+	tree, j := newJournaledTree()
+	before := tree.Hash()
+
+	j.Begin()
+	tree.SetNoValidate([]string{"a", "b", "c"})
+	tree.Child("Test3").MarkDeleted(ClearChildFlags)
+	j.End()
+
+	if !j.Undo() {
+		t.Fatal("nothing to undo")
+	}
+	if tree.Hash() != before {
+		t.Error("undo did not revert the whole change")
+	}
+	if j.CanUndo() {
+		t.Error("grouped change recorded as multiple changes")
+	}
+}
+
+func TestJournalUndoRestoresChildIndex(t *testing.T) {
+	tree, j := newJournaledTree()
+	next := tree.nxtChildIdx
+	tree.AddChild(New("new"))
+	j.Undo()
+	if tree.nxtChildIdx != next {
+		t.Errorf("next child index not restored, expected %d got %d",
+			next, tree.nxtChildIdx)
+	}
+	if tree.Child("new") != nil {
+		t.Error("added child still present after undo")
+	}
+}
+
+func TestJournalNewChangeDiscardsRedo(t *testing.T) {
+	tree, j := newJournaledTree()
+	tree.AddChild(New("a"))
+	j.Undo()
+	tree.AddChild(New("b"))
+	if j.CanRedo() {
+		t.Error("redo still available after new change")
+	}
+}
+
+func TestJournalRollbackToSavepoint(t *testing.T) {
+	tree, j := newJournaledTree()
+	tree.AddChild(New("kept"))
+	sp := j.Savepoint()
+	saved := tree.Hash()
+
+	tree.AddChild(New("a"))
+	tree.Child("Test3").MarkDeleted(ClearChildFlags)
+	tree.Child("kept").SetComment("changed")
+
+	if err := j.RollbackTo(sp); err != nil {
+		t.Fatalf("Unexpected rollback error: %s", err)
+	}
+	if tree.Hash() != saved {
+		t.Error("rollback did not restore savepoint")
+	}
+	if tree.Child("kept") == nil {
+		t.Error("change before savepoint was rolled back")
+	}
+	if j.CanRedo() {
+		t.Error("rolled back changes available to redo")
+	}
+}
+
+func TestJournalSavepointInvalidAfterUndo(t *testing.T) {
+	tree, j := newJournaledTree()
+	tree.AddChild(New("a"))
+	sp := j.Savepoint()
+	j.Undo()
+	tree.AddChild(New("b"))
+	if err := j.RollbackTo(sp); err != ErrSavepointInvalid {
+		t.Errorf("Expected ErrSavepointInvalid, got %v", err)
+	}
+}
+
+func TestJournalInitialSavepoint(t *testing.T) {
+	tree, j := newJournaledTree()
+	sp := j.Savepoint()
+	before := tree.Hash()
+	tree.AddChild(New("a"))
+	tree.AddChild(New("b"))
+	if err := j.RollbackTo(sp); err != nil {
+		t.Fatalf("Unexpected rollback error: %s", err)
+	}
+	if tree.Hash() != before {
+		t.Error("rollback did not restore initial state")
+	}
+}
+
+func TestDetachedNodesNotJournaled(t *testing.T) {
+	tree, j := newJournaledTree()
+	ch := New("detached")
+	ch.SetComment("comment")
+	ch.MarkOpaque()
+	if j.CanUndo() {
+		t.Error("change to detached node was journaled")
+	}
+	tree.AddChild(ch)
+	j.Undo()
+	if ch.Comment() != "comment" || !ch.Opaque() {
+		t.Error("undoing add modified the detached node")
+	}
+}
+
+func TestJournalRollbackWithinChange(t *testing.T) {
+	tree, j := newJournaledTree()
+	j.Begin()
+	tree.AddChild(New("kept"))
+	sp := j.Savepoint()
+	saved := tree.Hash()
+
+	j.Begin()
+	tree.AddChild(New("a"))
+	tree.Child("kept").SetComment("changed")
+	j.End()
+
+	if err := j.RollbackTo(sp); err != nil {
+		t.Fatalf("Unexpected rollback error: %s", err)
+	}
+	if tree.Hash() != saved {
+		t.Error("rollback did not revert the open change")
+	}
+	j.End()
+
+	if !j.Undo() || tree.Child("kept") != nil {
+		t.Error("change before savepoint not kept as one change")
+	}
+	if j.CanUndo() {
+		t.Error("rolled back entries left in the journal")
+	}
+}
+
+func TestJournalRollbackOpenChange(t *testing.T) {
+	tree, j := newJournaledTree()
+	tree.AddChild(New("kept"))
+	sp := j.Savepoint()
+	saved := tree.Hash()
+
+	j.Begin()
+	tree.AddChild(New("a"))
+	if err := j.RollbackTo(sp); err != nil {
+		t.Fatalf("Unexpected rollback error: %s", err)
+	}
+	j.End()
+	if tree.Hash() != saved {
+		t.Error("rollback did not revert the open change")
+	}
+	if !j.Undo() || j.CanUndo() {
+		t.Error("empty change was journaled")
+	}
+}
+
+func TestJournalNilRollback(t *testing.T) {
+	var j *Journal
+	if err := j.RollbackTo(Savepoint{}); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	tree, other := newJournaledTree()
+	tree.AddChild(New("a"))
+	if err := j.RollbackTo(other.Savepoint()); err != ErrSavepointInvalid {
+		t.Errorf("Expected ErrSavepointInvalid, got %v", err)
+	}
+}
+
+func TestJournalLimit(t *testing.T) {
+	tree, j := newJournaledTree()
+	initial := j.Savepoint()
+	j.SetLimit(2)
+	tree.AddChild(New("a"))
+	tree.AddChild(New("b"))
+	sp := j.Savepoint()
+	tree.AddChild(New("c"))
+	tree.AddChild(New("d"))
+
+	if err := j.RollbackTo(initial); err != ErrSavepointInvalid {
+		t.Errorf("Expected ErrSavepointInvalid, got %v", err)
+	}
+	if err := j.RollbackTo(sp); err != ErrSavepointInvalid {
+		t.Errorf("Expected ErrSavepointInvalid, got %v", err)
+	}
+	if !j.Undo() || !j.Undo() || j.Undo() {
+		t.Error("journal not limited to 2 changes")
+	}
+	if tree.Child("b") == nil || tree.Child("c") != nil {
+		t.Error("undo beyond limit")
+	}
+}
+
+func TestJournalNotify(t *testing.T) {
+	tree, j := newJournaledTree()
+	var sent []string
+	notify := func(j *Journal, name string) {
+		j.Notify(func() { sent = append(sent, "+"+name) },
+			func() { sent = append(sent, "-"+name) })
+	}
+
+	j.Begin()
+	tree.AddChild(New("a"))
+	notify(j, "a")
+	sp := j.Savepoint()
+	tree.AddChild(New("b"))
+	notify(j, "b")
+	if len(sent) != 0 {
+		t.Errorf("notified before change complete: %v", sent)
+	}
+	if err := j.RollbackTo(sp); err != nil {
+		t.Fatal(err)
+	}
+	j.End()
+	j.Undo()
+	j.Redo()
+	notify(j, "c")
+	notify(nil, "d")
+
+	expect := []string{"+a", "-a", "+a", "+c", "+d"}
+	if !reflect.DeepEqual(sent, expect) {
+		t.Errorf("Expected notifications %v, got %v", expect, sent)
+	}
+}
@@ -10,6 +10,7 @@
 package data
 
 import (
+	"sync"
 	"sync/atomic"
 
 	"github.com/danos/utils/natsort"
@@ -19,6 +20,7 @@ const (
 	flagDeleted uint32 = 1 << iota
 	flagOpaque
 	flagDefault
+	flagOrdered
 )
 
 const (
@@ -49,6 +51,22 @@ func (t *AtomicNode) Store(n *Node) {
 	t.Value.Store(n)
 }
 
+// Publish makes the persistent tree p the current version. As p can't be
+// modified, every reader of this version, and every Snapshot of it,
+// shares p rather than copying it.
+func (t *AtomicNode) Publish(p *Persistent) {
+	t.Store(p.Thaw())
+}
+
+// Snapshot returns a copy of the current version that later versions,
+// and changes to the copy, don't affect. A version stored with Publish is
+// copied in O(1); otherwise only the nodes created or read since it was
+// thawed are visited. The current version must not be modified while the
+// snapshot is taken.
+func (t *AtomicNode) Snapshot() *Node {
+	return Freeze(t.Load()).Thaw()
+}
+
 func NewAtomicNode(n *Node) *AtomicNode {
 	a := &AtomicNode{}
 	if n == nil {
@@ -67,6 +85,20 @@ type Node struct {
 	children    map[string]*Node
 	idx         uint64
 	nxtChildIdx uint64
+	//annotations holds RFC 7952 metadata keyed by the
+	//module qualified annotation name, eg "ietf-origin:origin"
+	annotations map[string]string
+	//frozen is the Persistent node this node was thawed from, for as
+	//long as the node itself is unmodified. Its children are only
+	//thawed, once, when first needed.
+	frozen   *Persistent
+	thawOnce sync.Once
+	thawed   uint32
+	//parent is the node this node was last added to, it is used
+	//to find the journal of the tree
+	parent *Node
+	//journal is only set on the root of a journaled tree
+	journal *Journal
 }
 
 func New(name string) *Node {
@@ -76,11 +108,36 @@ func New(name string) *Node {
 	}
 }
 
+// thaw creates the children of a node thawed from a Persistent tree.
+func (n *Node) thaw() {
+	if n.frozen == nil {
+		return
+	}
+	n.thawOnce.Do(func() {
+		children := make(map[string]*Node, n.frozen.nchildren)
+		n.frozen.children.each(func(name string, ch *Persistent) {
+			thawed := ch.Thaw()
+			thawed.parent = n
+			children[name] = thawed
+		})
+		n.children = children
+		atomic.StoreUint32(&n.thawed, 1)
+	})
+}
+
+// modify is called before any change to n, which no longer matches the
+// Persistent node it was thawed from.
+func (n *Node) modify() {
+	n.thaw()
+	n.frozen = nil
+}
+
 func (n *Node) Copy() *Node {
 	return &Node{
-		name:     n.name,
-		comment:  n.comment,
-		children: make(map[string]*Node),
+		name:        n.name,
+		comment:     n.comment,
+		annotations: copyAnnotations(n.annotations),
+		children:    make(map[string]*Node),
 	}
 }
 
@@ -88,6 +145,7 @@ func (n *Node) Child(name string) *Node {
 	if n == nil {
 		return nil
 	}
+	n.thaw()
 	return n.children[name]
 }
 
@@ -95,26 +153,56 @@ func (n *Node) AddChild(child *Node) {
 	if child == nil {
 		return
 	}
-	child.SetIndex(n.nxtChildIdx)
-	/* 64bit counter this is 34 million years at
-	 * current rpc rates to overflow, so I don't care about overflow
-	 * This is lifetime of the session only. */
-	n.nxtChildIdx++
-	n.children[child.Name()] = child
+	j := n.Journal()
+	j.Begin()
+	defer j.End()
+	n.recordChild(child.Name(), func() {
+		child.SetIndex(n.nxtChildIdx)
+		/* 64bit counter this is 34 million years at
+		 * current rpc rates to overflow, so I don't care about overflow
+		 * This is lifetime of the session only. */
+		n.nxtChildIdx++
+		if old, ok := n.children[child.Name()]; ok && old.parent == n {
+			old.parent = nil
+		}
+		n.children[child.Name()] = child
+		child.parent = n
+	})
 }
 
 func (n *Node) DeleteChild(name string) {
-	delete(n.children, name)
+	n.thaw()
+	ch, ok := n.children[name]
+	if !ok {
+		return
+	}
+	n.recordChild(name, func() {
+		if ch.parent == n {
+			ch.parent = nil
+		}
+		delete(n.children, name)
+	})
 }
 
 func (n *Node) ClearChildren() {
+	n.modify()
+	old := n.children
+	for _, ch := range old {
+		if ch.parent == n {
+			ch.parent = nil
+		}
+	}
 	n.children = make(map[string]*Node)
+	if j := n.Journal(); j != nil && len(old) > 0 {
+		j.record(&childrenEntry{parent: n, old: old, new: n.children})
+	}
 }
 
 func (n *Node) ChildNames() []string {
 	if n == nil {
 		return nil
 	}
+	n.thaw()
 	children := make([]string, 0, len(n.children))
 	for _, v := range n.children {
 		children = append(children, v.Name())
@@ -129,6 +217,7 @@ func (n *Node) Children() []*Node {
 	/* Return a list of nodes in iteration order of the map;
 	 * this means there is no guaranteed ordering. The upper
 	 * layer is expected to do sorting based on schema information. */
+	n.thaw()
 	children := make([]*Node, 0, len(n.children))
 	for _, v := range n.children {
 		children = append(children, v)
@@ -140,13 +229,16 @@ func (n *Node) NumChildren() int {
 	if n == nil {
 		return 0
 	}
+	n.thaw()
 	return len(n.children)
 }
 
+// ChildMap returns the children by name. The map must not be modified.
 func (n *Node) ChildMap() map[string]*Node {
 	if n == nil {
 		return make(map[string]*Node)
 	}
+	n.thaw()
 	return n.children
 }
 
@@ -159,7 +251,12 @@ func (n *Node) Index() uint64 {
 }
 
 func (n *Node) SetIndex(idx uint64) {
-	n.idx = idx
+	if n.idx == idx {
+		return
+	}
+	n.recordState(func() {
+		n.idx = idx
+	})
 }
 
 func (n *Node) Comment() string {
@@ -167,7 +264,18 @@ func (n *Node) Comment() string {
 }
 
 func (n *Node) SetComment(comment string) {
-	n.comment = comment
+	n.recordState(func() {
+		n.comment = comment
+	})
+}
+
+func (n *Node) setFlags(flags uint32) {
+	if n.flags == flags {
+		return
+	}
+	n.recordState(func() {
+		n.flags = flags
+	})
 }
 
 func (n *Node) Deleted() bool {
@@ -182,6 +290,12 @@ func (n *Node) Default() bool {
 	return n.flags&flagDefault == flagDefault
 }
 
+// Ordered is set on an overlay node whose children's user order
+// replaces that of the underlay.
+func (n *Node) Ordered() bool {
+	return n.flags&flagOrdered == flagOrdered
+}
+
 // Depending on the type of delete, we may or may not clear the children's
 // flags.  When checking authorization on each node, we don't clear, eg
 // when we are doing the likes of a load operation and
@@ -189,33 +303,47 @@ func (n *Node) Default() bool {
 // cleared the flags on the children, then when we add the new config on top
 // of previous deletions, they will reappear if their parent is recreated.
 func (n *Node) MarkDeleted(clearChildFlagsWhenDeletingParent bool) {
-	n.flags = n.flags | flagDeleted | flagOpaque
+	j := n.Journal()
+	j.Begin()
+	defer j.End()
+	n.setFlags(n.flags | flagDeleted | flagOpaque)
 	if clearChildFlagsWhenDeletingParent {
 		n.ClearChildren()
 	}
 }
 
 func (n *Node) MarkOpaque() {
-	n.flags = n.flags | flagOpaque
+	n.setFlags(n.flags | flagOpaque)
 }
 
 func (n *Node) MarkDefault() {
-	n.flags = n.flags | flagDefault
+	n.setFlags(n.flags | flagDefault)
+}
+
+func (n *Node) MarkOrdered() {
+	n.setFlags(n.flags | flagOrdered)
 }
 
 func (n *Node) ClearDeleted() {
-	n.flags = n.flags &^ flagDeleted
+	n.setFlags(n.flags &^ flagDeleted)
 }
 
 func (n *Node) ClearOpaque() {
-	n.flags = n.flags &^ flagOpaque
+	n.setFlags(n.flags &^ flagOpaque)
 }
 
 func (n *Node) ClearDefault() {
-	n.flags = n.flags &^ flagDefault
+	n.setFlags(n.flags &^ flagDefault)
+}
+
+func (n *Node) ClearOrdered() {
+	n.setFlags(n.flags &^ flagOrdered)
 }
 
 func (n *Node) SetNoValidate(path []string) {
+	j := n.Journal()
+	j.Begin()
+	defer j.End()
 	n.setNoValidateInternal(path, make([]string, 0, len(path)))
 }
 
@@ -164,3 +164,24 @@ func TestSetNoValidate(t *testing.T) {
 		t.Fatal("did not find expected child bar")
 	}
 }
+
+func TestAnnotations(t *testing.T) {
+	tree := createBaseTree()
+	ch := tree.Child("Test0")
+	ch.SetAnnotation("test:modified-by", "alice")
+	if v, ok := ch.Annotation("test:modified-by"); !ok || v != "alice" {
+		t.Fatal("unexpected annotation value")
+	}
+	anns := ch.Annotations()
+	anns["test:other"] = "changed"
+	if _, ok := ch.Annotation("test:other"); ok {
+		t.Fatal("Annotations() did not return a copy")
+	}
+	if cp := ch.Copy(); len(cp.Annotations()) != 1 {
+		t.Fatal("annotations not copied")
+	}
+	ch.DeleteAnnotation("test:modified-by")
+	if ch.Annotations() != nil {
+		t.Fatal("annotation not deleted")
+	}
+}
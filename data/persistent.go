@@ -0,0 +1,395 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Persistent is an immutable, structurally shared version of Node.
+//
+// None of the methods modify the receiver; the 'mutators' return a new
+// Persistent that shares every unmodified subtree with the original.
+// Taking a snapshot of a tree is therefore just keeping hold of the
+// pointer, and a change to a descendant only copies the nodes on the
+// path from the root to that descendant.
+type Persistent struct {
+	name    string
+	comment string
+	flags   uint32
+	//32bit hole
+	annotations map[string]string
+	children    *pmapNode
+	nchildren   int
+	idx         uint64
+	nxtChildIdx uint64
+	//hash caches the Digest of the subtree; as the subtree
+	//can't change it never needs to be invalidated.
+	hash atomic.Value
+}
+
+func NewPersistent(name string) *Persistent {
+	return &Persistent{name: name}
+}
+
+// Freeze builds a Persistent tree from the contents of a mutable Node.
+// The Node is not retained and may continue to be modified afterwards.
+// Parts of the Node that were thawed from a Persistent tree and have not
+// been modified since are shared rather than rebuilt.
+func Freeze(n *Node) *Persistent {
+	if n == nil {
+		return nil
+	}
+	if n.frozen != nil && atomic.LoadUint32(&n.thawed) == 0 {
+		return n.frozen
+	}
+	n.thaw()
+	children := make(map[string]*Persistent, len(n.children))
+	unchanged := n.frozen != nil
+	for name, ch := range n.children {
+		children[name] = Freeze(ch)
+		if unchanged && n.frozen.Child(name) != children[name] {
+			unchanged = false
+		}
+	}
+	if unchanged {
+		return n.frozen
+	}
+	out := &Persistent{
+		name:        n.name,
+		comment:     n.comment,
+		flags:       n.flags,
+		annotations: copyAnnotations(n.annotations),
+		idx:         n.idx,
+		nxtChildIdx: n.nxtChildIdx,
+	}
+	for name, ch := range children {
+		out.children, _ = out.children.set(pmapHash(name), name, ch, 0)
+		out.nchildren++
+	}
+	return out
+}
+
+// Thaw returns a mutable copy of the tree, for use with the APIs that
+// operate on Node (eg as the underlay of a union tree). The copy is made
+// in O(1); the children of each node are only thawed when first used, so
+// the cost is proportional to the part of the tree that is used.
+func (p *Persistent) Thaw() *Node {
+	if p == nil {
+		return nil
+	}
+	return &Node{
+		name:        p.name,
+		comment:     p.comment,
+		flags:       p.flags,
+		annotations: copyAnnotations(p.annotations),
+		idx:         p.idx,
+		nxtChildIdx: p.nxtChildIdx,
+		frozen:      p,
+	}
+}
+
+// clone copies p, other than its cached hash, ready to be modified.
+func (p *Persistent) clone() *Persistent {
+	return &Persistent{
+		name:        p.name,
+		comment:     p.comment,
+		flags:       p.flags,
+		annotations: p.annotations,
+		children:    p.children,
+		nchildren:   p.nchildren,
+		idx:         p.idx,
+		nxtChildIdx: p.nxtChildIdx,
+	}
+}
+
+func (p *Persistent) Name() string {
+	return p.name
+}
+
+func (p *Persistent) Index() uint64 {
+	return p.idx
+}
+
+func (p *Persistent) Comment() string {
+	return p.comment
+}
+
+func (p *Persistent) Deleted() bool {
+	return p.flags&flagDeleted == flagDeleted
+}
+
+func (p *Persistent) Opaque() bool {
+	return p.flags&flagOpaque == flagOpaque
+}
+
+func (p *Persistent) Default() bool {
+	return p.flags&flagDefault == flagDefault
+}
+
+func (p *Persistent) Ordered() bool {
+	return p.flags&flagOrdered == flagOrdered
+}
+
+func (p *Persistent) Child(name string) *Persistent {
+	if p == nil {
+		return nil
+	}
+	return p.children.get(pmapHash(name), name, 0)
+}
+
+func (p *Persistent) NumChildren() int {
+	if p == nil {
+		return 0
+	}
+	return p.nchildren
+}
+
+func (p *Persistent) ChildNames() []string {
+	if p == nil {
+		return nil
+	}
+	out := make([]string, 0, p.nchildren)
+	p.children.each(func(name string, _ *Persistent) {
+		out = append(out, name)
+	})
+	return out
+}
+
+func (p *Persistent) Children() []*Persistent {
+	if p == nil {
+		return nil
+	}
+	/* As with Node, there is no guaranteed ordering. */
+	out := make([]*Persistent, 0, p.nchildren)
+	p.children.each(func(_ string, ch *Persistent) {
+		out = append(out, ch)
+	})
+	return out
+}
+
+// AddChild returns a copy of p with child added, replacing any existing
+// child of the same name. As with Node.AddChild the child is given the
+// next index for user ordering.
+func (p *Persistent) AddChild(child *Persistent) *Persistent {
+	if child == nil {
+		return p
+	}
+	if p == nil {
+		p = &Persistent{}
+	}
+	child = child.SetIndex(p.nxtChildIdx)
+	out := p.clone()
+	out.nxtChildIdx++
+	var added bool
+	out.children, added = out.children.set(
+		pmapHash(child.name), child.name, child, 0)
+	if added {
+		out.nchildren++
+	}
+	return out
+}
+
+// ReplaceChild returns a copy of p with child substituted for the
+// existing child of the same name, keeping the child's own index.
+func (p *Persistent) ReplaceChild(child *Persistent) *Persistent {
+	if child == nil {
+		return p
+	}
+	out := p.clone()
+	var added bool
+	out.children, added = out.children.set(
+		pmapHash(child.name), child.name, child, 0)
+	if added {
+		out.nchildren++
+	}
+	return out
+}
+
+func (p *Persistent) DeleteChild(name string) *Persistent {
+	children, removed := p.children.remove(pmapHash(name), name, 0)
+	if !removed {
+		return p
+	}
+	out := p.clone()
+	out.children = children
+	out.nchildren--
+	return out
+}
+
+func (p *Persistent) ClearChildren() *Persistent {
+	out := p.clone()
+	out.children = nil
+	out.nchildren = 0
+	return out
+}
+
+func (p *Persistent) SetIndex(idx uint64) *Persistent {
+	if p.idx == idx {
+		return p
+	}
+	out := p.clone()
+	out.idx = idx
+	return out
+}
+
+func (p *Persistent) SetComment(comment string) *Persistent {
+	out := p.clone()
+	out.comment = comment
+	return out
+}
+
+func (p *Persistent) setFlags(flags uint32) *Persistent {
+	if p.flags == flags {
+		return p
+	}
+	out := p.clone()
+	out.flags = flags
+	return out
+}
+
+func (p *Persistent) MarkDeleted(clearChildFlagsWhenDeletingParent bool) *Persistent {
+	out := p.setFlags(p.flags | flagDeleted | flagOpaque)
+	if clearChildFlagsWhenDeletingParent {
+		out = out.ClearChildren()
+	}
+	return out
+}
+
+func (p *Persistent) MarkOpaque() *Persistent {
+	return p.setFlags(p.flags | flagOpaque)
+}
+
+func (p *Persistent) MarkDefault() *Persistent {
+	return p.setFlags(p.flags | flagDefault)
+}
+
+func (p *Persistent) MarkOrdered() *Persistent {
+	return p.setFlags(p.flags | flagOrdered)
+}
+
+func (p *Persistent) ClearDeleted() *Persistent {
+	return p.setFlags(p.flags &^ flagDeleted)
+}
+
+func (p *Persistent) ClearOpaque() *Persistent {
+	return p.setFlags(p.flags &^ flagOpaque)
+}
+
+func (p *Persistent) ClearDefault() *Persistent {
+	return p.setFlags(p.flags &^ flagDefault)
+}
+
+func (p *Persistent) ClearOrdered() *Persistent {
+	return p.setFlags(p.flags &^ flagOrdered)
+}
+
+// Descendant returns the node at path, or nil if it does not exist.
+func (p *Persistent) Descendant(path []string) *Persistent {
+	for _, elem := range path {
+		p = p.Child(elem)
+		if p == nil {
+			return nil
+		}
+	}
+	return p
+}
+
+// Update applies fn to the node at path and returns a new root with
+// only the nodes along path copied. Missing nodes along the path are
+// created as they would be by Node.SetNoValidate; fn receives nil if the
+// final node does not yet exist. If fn returns nil the node is removed.
+func (p *Persistent) Update(
+	path []string,
+	fn func(*Persistent) *Persistent,
+) *Persistent {
+	if len(path) == 0 {
+		return fn(p)
+	}
+	if p == nil {
+		p = &Persistent{}
+	}
+	hd, tl := path[0], path[1:]
+	ch := p.Child(hd)
+	if ch == nil && len(tl) > 0 {
+		ch = NewPersistent(hd)
+	}
+	var newch *Persistent
+	if len(tl) == 0 {
+		newch = fn(ch)
+	} else {
+		newch = ch.Update(tl, fn)
+	}
+	switch {
+	case newch == ch:
+		return p
+	case newch == nil:
+		return p.DeleteChild(hd)
+	case p.Child(hd) == nil:
+		return p.AddChild(newch)
+	default:
+		return p.ReplaceChild(newch)
+	}
+}
+
+// Set returns a tree with path created, the persistent equivalent of
+// Node.SetNoValidate.
+func (p *Persistent) Set(path []string) *Persistent {
+	return p.Update(path, func(n *Persistent) *Persistent {
+		if n != nil {
+			return n
+		}
+		return NewPersistent(path[len(path)-1])
+	})
+}
+
+// Delete returns a tree with the node at path removed.
+func (p *Persistent) Delete(path []string) *Persistent {
+	if p.Descendant(path) == nil {
+		return p
+	}
+	return p.Update(path, func(*Persistent) *Persistent {
+		return nil
+	})
+}
+
+// AtomicPersistent publishes versions of a Persistent tree. Readers
+// always see a complete version as versions are never modified in
+// place; writers are serialized so that concurrent Updates are not lost.
+type AtomicPersistent struct {
+	value atomic.Value
+	mu    sync.Mutex
+}
+
+func NewAtomicPersistent(p *Persistent) *AtomicPersistent {
+	a := &AtomicPersistent{}
+	if p == nil {
+		a.Store(NewPersistent("root"))
+	} else {
+		a.Store(p)
+	}
+	return a
+}
+
+func (t *AtomicPersistent) Load() *Persistent {
+	return t.value.Load().(*Persistent)
+}
+
+func (t *AtomicPersistent) Store(p *Persistent) {
+	t.mu.Lock()
+	t.value.Store(p)
+	t.mu.Unlock()
+}
+
+// Update publishes the result of applying fn to the current version
+// and returns it.
+func (t *AtomicPersistent) Update(fn func(*Persistent) *Persistent) *Persistent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := fn(t.Load())
+	t.value.Store(p)
+	return p
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func createBasePersistent() *Persistent {
+	return Freeze(createBaseTree())
+}
+
+func TestFreezeThaw(t *testing.T) {
+	tree := createBaseTree()
+	tree.Child("Test3").SetComment("a comment")
+	tree.Child("Test4").MarkDeleted(DontClearChildFlags)
+
+	out := Freeze(tree).Thaw()
+	if out.NumChildren() != tree.NumChildren() {
+		t.Fatal("unexpected number of children")
+	}
+	for _, ch := range tree.Children() {
+		och := out.Child(ch.Name())
+		if och == nil {
+			t.Fatalf("did not find expected child %s", ch.Name())
+		}
+		if och.Index() != ch.Index() || och.Comment() != ch.Comment() ||
+			och.Deleted() != ch.Deleted() ||
+			och.NumChildren() != ch.NumChildren() {
+			t.Fatalf("child %s not preserved", ch.Name())
+		}
+	}
+	out.AddChild(New("Test10"))
+	if out.Child("Test10").Index() != 10 {
+		t.Fatal("next child index not preserved")
+	}
+}
+
+func TestPersistentSnapshot(t *testing.T) {
+	snap := createBasePersistent()
+	p := snap.Set([]string{"Test0", "foo", "bar"})
+	p = p.Delete([]string{"Test1"})
+	p = p.Update([]string{"Test2"}, func(n *Persistent) *Persistent {
+		return n.SetComment("changed")
+	})
+
+	if snap.Descendant([]string{"Test0", "foo"}) != nil {
+		t.Fatal("snapshot modified by Set")
+	}
+	if snap.Child("Test1") == nil {
+		t.Fatal("snapshot modified by Delete")
+	}
+	if snap.Child("Test2").Comment() != "" {
+		t.Fatal("snapshot modified by Update")
+	}
+
+	if p.Descendant([]string{"Test0", "foo", "bar"}) == nil {
+		t.Fatal("did not find expected child bar")
+	}
+	if p.Child("Test1") != nil {
+		t.Fatal("found unexpected child")
+	}
+	if p.Child("Test2").Comment() != "changed" {
+		t.Fatal("unexpected comment")
+	}
+	if p.NumChildren() != 9 {
+		t.Fatal("unexpected number of children")
+	}
+
+	// Untouched subtrees are shared, not copied.
+	if p.Child("Test5") != snap.Child("Test5") {
+		t.Fatal("unmodified child was copied")
+	}
+}
+
+func TestPersistentManyChildren(t *testing.T) {
+	const count = 5000
+	p := NewPersistent("root")
+	for i := 0; i < count; i++ {
+		p = p.AddChild(NewPersistent(fmt.Sprintf("entry%d", i)))
+	}
+	if p.NumChildren() != count {
+		t.Fatal("unexpected number of children")
+	}
+	for i := 0; i < count; i += 2 {
+		p = p.DeleteChild(fmt.Sprintf("entry%d", i))
+	}
+	names := p.ChildNames()
+	sort.Strings(names)
+	if len(names) != count/2 {
+		t.Fatalf("unexpected number of children: %d", len(names))
+	}
+	for i := 1; i < count; i += 2 {
+		ch := p.Child(fmt.Sprintf("entry%d", i))
+		if ch == nil {
+			t.Fatalf("did not find expected child entry%d", i)
+		}
+		if ch.Index() != uint64(i) {
+			t.Fatalf("unexpected index for entry%d", i)
+		}
+	}
+}
+
+func TestPersistentFlags(t *testing.T) {
+	p := createBasePersistent()
+	ch := p.Child("Test0")
+	del := ch.MarkDeleted(ClearChildFlags)
+	if ch.Deleted() || ch.NumChildren() != 5 {
+		t.Fatal("MarkDeleted modified receiver")
+	}
+	if !del.Deleted() || !del.Opaque() || del.NumChildren() != 0 {
+		t.Fatal("unexpected flags after MarkDeleted")
+	}
+	if del.ClearDeleted().Deleted() {
+		t.Fatal("unexpected value for Deleted()")
+	}
+	if !ch.MarkDefault().Default() {
+		t.Fatal("unexpected value for Default()")
+	}
+}
+
+func TestAtomicPersistent(t *testing.T) {
+	a := NewAtomicPersistent(nil)
+	before := a.Load()
+	a.Update(func(p *Persistent) *Persistent {
+		return p.Set([]string{"foo", "bar"})
+	})
+	if before.Child("foo") != nil {
+		t.Fatal("published version modified")
+	}
+	if a.Load().Descendant([]string{"foo", "bar"}) == nil {
+		t.Fatal("update not published")
+	}
+}
+
+func TestThawShares(t *testing.T) {
+	p := createBasePersistent()
+	n := p.Thaw()
+	if Freeze(n) != p {
+		t.Fatal("unmodified tree was rebuilt")
+	}
+
+	n.Child("Test0").AddChild(New("foo"))
+	n.Child("Test1").Child("TestCh0").SetComment("changed")
+	out := Freeze(n)
+	if out == p {
+		t.Fatal("modified tree not rebuilt")
+	}
+	if out.Child("Test5") != p.Child("Test5") {
+		t.Fatal("unmodified child was copied")
+	}
+	if out.Child("Test1").Child("TestCh1") != p.Child("Test1").Child("TestCh1") {
+		t.Fatal("unmodified grandchild was copied")
+	}
+	if out.Descendant([]string{"Test0", "foo"}) == nil ||
+		out.Descendant([]string{"Test1", "TestCh0"}).Comment() != "changed" {
+		t.Fatal("changes not frozen")
+	}
+	if p.Descendant([]string{"Test0", "foo"}) != nil ||
+		p.Descendant([]string{"Test1", "TestCh0"}).Comment() != "" {
+		t.Fatal("thawed tree modified the persistent tree")
+	}
+}
+
+func TestAtomicNodeSnapshot(t *testing.T) {
+	p := createBasePersistent()
+	a := NewAtomicNode(nil)
+	a.Publish(p)
+
+	snap := a.Snapshot()
+	if Freeze(snap) != p {
+		t.Fatal("snapshot of published version was copied")
+	}
+	snap.Child("Test0").AddChild(New("foo"))
+	if a.Load().Child("Test0").Child("foo") != nil {
+		t.Fatal("change to snapshot modified current version")
+	}
+
+	a.Publish(p.Delete([]string{"Test1"}))
+	if snap.Child("Test1") == nil {
+		t.Fatal("snapshot modified by new version")
+	}
+}
+
+func TestPersistentNilRoot(t *testing.T) {
+	var p *Persistent
+	p = p.Set([]string{"foo", "bar"})
+	if p.Descendant([]string{"foo", "bar"}) == nil {
+		t.Fatal("did not find expected child bar")
+	}
+	p = nil
+	if p.Delete([]string{"foo"}) != nil {
+		t.Fatal("unexpected tree from nil root")
+	}
+}
+
+func TestThawConcurrentReaders(t *testing.T) {
+	a := NewAtomicNode(nil)
+	a.Publish(createBasePersistent())
+	n := a.Load()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, ch := range n.Children() {
+				if ch.NumChildren() != 5 {
+					t.Error("unexpected number of children")
+				}
+			}
+			Freeze(n)
+		}()
+	}
+	wg.Wait()
+}
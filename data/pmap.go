@@ -0,0 +1,223 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"math/bits"
+)
+
+// pmap is a persistent hash array mapped trie keyed by child name. Every
+// update returns a new trie which shares all untouched branches with the
+// original, so the cost of a modification is proportional to the depth of
+// the trie rather than to the number of children.
+const (
+	pmapBits = 5
+	pmapMask = 1<<pmapBits - 1
+)
+
+type pmapEntry struct {
+	key string
+	val *Persistent
+}
+
+type pmapSlot struct {
+	//sub is non-nil for interior slots
+	sub *pmapNode
+	//hash and entries are used for leaf slots, entries
+	//only holds more than one element on a full hash collision
+	hash    uint32
+	entries []pmapEntry
+}
+
+type pmapNode struct {
+	bitmap uint32
+	slots  []pmapSlot
+}
+
+func pmapHash(key string) uint32 {
+	//FNV-1a, inlined to avoid allocating a hash.Hash per lookup
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func (m *pmapNode) position(hash uint32, shift uint) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & pmapMask)
+	return bit, bits.OnesCount32(m.bitmap & (bit - 1))
+}
+
+func (m *pmapNode) get(hash uint32, key string, shift uint) *Persistent {
+	for m != nil {
+		bit, pos := m.position(hash, shift)
+		if m.bitmap&bit == 0 {
+			return nil
+		}
+		slot := &m.slots[pos]
+		if slot.sub != nil {
+			m = slot.sub
+			shift += pmapBits
+			continue
+		}
+		if slot.hash != hash {
+			return nil
+		}
+		for _, e := range slot.entries {
+			if e.key == key {
+				return e.val
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+func (m *pmapNode) clone() *pmapNode {
+	out := &pmapNode{
+		bitmap: m.bitmap,
+		slots:  make([]pmapSlot, len(m.slots)),
+	}
+	copy(out.slots, m.slots)
+	return out
+}
+
+func newPmapLeaf(hash uint32, key string, val *Persistent) pmapSlot {
+	return pmapSlot{
+		hash:    hash,
+		entries: []pmapEntry{{key: key, val: val}},
+	}
+}
+
+// mergePmapLeaves builds the interior node needed to hold two leaf slots whose
+// hashes differ, recursing until the hashes diverge.
+func mergePmapLeaves(a, b pmapSlot, shift uint) *pmapNode {
+	ia := (a.hash >> shift) & pmapMask
+	ib := (b.hash >> shift) & pmapMask
+	if ia == ib {
+		return &pmapNode{
+			bitmap: uint32(1) << ia,
+			slots:  []pmapSlot{{sub: mergePmapLeaves(a, b, shift+pmapBits)}},
+		}
+	}
+	out := &pmapNode{bitmap: uint32(1)<<ia | uint32(1)<<ib}
+	if ia < ib {
+		out.slots = []pmapSlot{a, b}
+	} else {
+		out.slots = []pmapSlot{b, a}
+	}
+	return out
+}
+
+// set returns a new trie with key bound to val, and whether the key
+// was not previously present.
+func (m *pmapNode) set(hash uint32, key string, val *Persistent, shift uint) (*pmapNode, bool) {
+	if m == nil {
+		m = &pmapNode{}
+	}
+	bit, pos := m.position(hash, shift)
+	out := m.clone()
+	if m.bitmap&bit == 0 {
+		out.bitmap |= bit
+		out.slots = append(out.slots, pmapSlot{})
+		copy(out.slots[pos+1:], out.slots[pos:])
+		out.slots[pos] = newPmapLeaf(hash, key, val)
+		return out, true
+	}
+	slot := m.slots[pos]
+	switch {
+	case slot.sub != nil:
+		sub, added := slot.sub.set(hash, key, val, shift+pmapBits)
+		out.slots[pos] = pmapSlot{sub: sub}
+		return out, added
+	case slot.hash == hash:
+		entries := make([]pmapEntry, len(slot.entries), len(slot.entries)+1)
+		copy(entries, slot.entries)
+		for i, e := range entries {
+			if e.key == key {
+				entries[i].val = val
+				out.slots[pos] = pmapSlot{hash: hash, entries: entries}
+				return out, false
+			}
+		}
+		entries = append(entries, pmapEntry{key: key, val: val})
+		out.slots[pos] = pmapSlot{hash: hash, entries: entries}
+		return out, true
+	default:
+		out.slots[pos] = pmapSlot{
+			sub: mergePmapLeaves(slot, newPmapLeaf(hash, key, val),
+				shift+pmapBits),
+		}
+		return out, true
+	}
+}
+
+// remove returns a new trie without key, and whether the key was present.
+// A nil trie is returned once the last entry has been removed.
+func (m *pmapNode) remove(hash uint32, key string, shift uint) (*pmapNode, bool) {
+	if m == nil {
+		return nil, false
+	}
+	bit, pos := m.position(hash, shift)
+	if m.bitmap&bit == 0 {
+		return m, false
+	}
+	slot := m.slots[pos]
+	var replacement pmapSlot
+	switch {
+	case slot.sub != nil:
+		sub, removed := slot.sub.remove(hash, key, shift+pmapBits)
+		if !removed {
+			return m, false
+		}
+		replacement = pmapSlot{sub: sub}
+	case slot.hash != hash:
+		return m, false
+	default:
+		entries := make([]pmapEntry, 0, len(slot.entries))
+		for _, e := range slot.entries {
+			if e.key != key {
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == len(slot.entries) {
+			return m, false
+		}
+		if len(entries) > 0 {
+			replacement = pmapSlot{hash: hash, entries: entries}
+		}
+	}
+	if replacement.sub == nil && len(replacement.entries) == 0 {
+		if len(m.slots) == 1 {
+			return nil, true
+		}
+		out := &pmapNode{
+			bitmap: m.bitmap &^ bit,
+			slots:  make([]pmapSlot, 0, len(m.slots)-1),
+		}
+		out.slots = append(out.slots, m.slots[:pos]...)
+		out.slots = append(out.slots, m.slots[pos+1:]...)
+		return out, true
+	}
+	out := m.clone()
+	out.slots[pos] = replacement
+	return out, true
+}
+
+func (m *pmapNode) each(fn func(string, *Persistent)) {
+	if m == nil {
+		return
+	}
+	for _, slot := range m.slots {
+		if slot.sub != nil {
+			slot.sub.each(fn)
+			continue
+		}
+		for _, e := range slot.entries {
+			fn(e.key, e.val)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// Snapshots are a compact binary representation of a Node tree which,
+// unlike the curly brace text format, preserves flags, user order indices
+// and comments exactly.
+//
+// The format is:
+//
+//	magic    "DCFG"
+//	version  1 byte
+//	node     name, comment, annotations, flags, index, next child index,
+//	         child count, followed by each child node in turn, sorted by name
+//	checksum CRC-32C of all preceding bytes, 4 bytes big endian
+//
+// Strings are a uvarint length followed by the bytes, all integers are
+// uvarints. Annotations are a count followed by name and value strings;
+// they are absent in version 1 snapshots.
+const (
+	snapshotMagic   = "DCFG"
+	SnapshotVersion = 2
+
+	maxSnapshotString = 16 << 20
+)
+
+var (
+	ErrSnapshotMagic    = errors.New("not a configuration snapshot")
+	ErrSnapshotChecksum = errors.New("configuration snapshot checksum mismatch")
+)
+
+var snapshotTable = crc32.MakeTable(crc32.Castagnoli)
+
+type SnapshotEncoder struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func NewSnapshotEncoder(w io.Writer) *SnapshotEncoder {
+	return &SnapshotEncoder{
+		w:   bufio.NewWriter(w),
+		crc: crc32.New(snapshotTable),
+	}
+}
+
+func (e *SnapshotEncoder) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	e.crc.Write(b)
+	_, e.err = e.w.Write(b)
+}
+
+func (e *SnapshotEncoder) writeUvarint(v uint64) {
+	n := binary.PutUvarint(e.buf[:], v)
+	e.write(e.buf[:n])
+}
+
+func (e *SnapshotEncoder) writeString(s string) {
+	e.writeUvarint(uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *SnapshotEncoder) encodeNode(n *Node) {
+	e.writeString(n.name)
+	e.writeString(n.comment)
+	e.writeUvarint(uint64(len(n.annotations)))
+	for _, name := range SortedAnnotationNames(n.annotations) {
+		e.writeString(name)
+		e.writeString(n.annotations[name])
+	}
+	e.writeUvarint(uint64(n.flags))
+	e.writeUvarint(n.idx)
+	e.writeUvarint(n.nxtChildIdx)
+	children := n.Children()
+	//Sorted so that the same tree always has the same encoding.
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].name < children[j].name
+	})
+	e.writeUvarint(uint64(len(children)))
+	for _, ch := range children {
+		e.encodeNode(ch)
+	}
+}
+
+// Encode writes a complete snapshot of the tree rooted at n, including
+// the checksum trailer, and flushes it to the underlying writer.
+func (e *SnapshotEncoder) Encode(n *Node) error {
+	if n == nil {
+		return errors.New("cannot encode nil node")
+	}
+	e.crc.Reset()
+	e.write([]byte(snapshotMagic))
+	e.write([]byte{SnapshotVersion})
+	e.encodeNode(n)
+	if e.err != nil {
+		return e.err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], e.crc.Sum32())
+	if _, err := e.w.Write(sum[:]); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// SnapshotDecoder reads snapshots from a stream, building the tree as the
+// stream is read rather than reading the whole snapshot in first.
+type SnapshotDecoder struct {
+	r       *bufio.Reader
+	crc     hash.Hash32
+	version byte
+}
+
+func NewSnapshotDecoder(r io.Reader) *SnapshotDecoder {
+	return &SnapshotDecoder{
+		r:   bufio.NewReader(r),
+		crc: crc32.New(snapshotTable),
+	}
+}
+
+func (d *SnapshotDecoder) read(b []byte) error {
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	d.crc.Write(b)
+	return nil
+}
+
+// byteReader adapts the SnapshotDecoder to io.ByteReader for the varint
+// decoding, without exporting ReadByte on the SnapshotDecoder itself.
+type byteReader SnapshotDecoder
+
+func (d *byteReader) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	d.crc.Write([]byte{b})
+	return b, nil
+}
+
+func (d *SnapshotDecoder) readUvarint() (uint64, error) {
+	return binary.ReadUvarint((*byteReader)(d))
+}
+
+func (d *SnapshotDecoder) readString() (string, error) {
+	l, err := d.readUvarint()
+	if err != nil {
+		return "", err
+	}
+	if l > maxSnapshotString {
+		return "", fmt.Errorf("snapshot string length %d too large", l)
+	}
+	b := make([]byte, l)
+	if err := d.read(b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (d *SnapshotDecoder) decodeAnnotations(n *Node) error {
+	count, err := d.readUvarint()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < count; i++ {
+		name, err := d.readString()
+		if err != nil {
+			return err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return err
+		}
+		n.SetAnnotation(name, value)
+	}
+	return nil
+}
+
+func (d *SnapshotDecoder) decodeNode() (*Node, error) {
+	var err error
+	n := New("")
+	if n.name, err = d.readString(); err != nil {
+		return nil, err
+	}
+	if n.comment, err = d.readString(); err != nil {
+		return nil, err
+	}
+	if d.version >= 2 {
+		if err := d.decodeAnnotations(n); err != nil {
+			return nil, err
+		}
+	}
+	flags, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	n.flags = uint32(flags)
+	if n.idx, err = d.readUvarint(); err != nil {
+		return nil, err
+	}
+	if n.nxtChildIdx, err = d.readUvarint(); err != nil {
+		return nil, err
+	}
+	nchildren, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nchildren; i++ {
+		ch, err := d.decodeNode()
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := n.children[ch.name]; exists {
+			return nil, fmt.Errorf("duplicate child %s in snapshot", ch.name)
+		}
+		n.children[ch.name] = ch
+	}
+	return n, nil
+}
+
+// Decode reads the next snapshot from the stream. io.EOF is returned
+// if there are no more snapshots; a truncated or corrupted snapshot
+// results in a different error.
+func (d *SnapshotDecoder) Decode() (*Node, error) {
+	d.crc.Reset()
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(d.r, magic); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, ErrSnapshotMagic
+		}
+		return nil, err
+	}
+	d.crc.Write(magic)
+	if string(magic) != snapshotMagic {
+		return nil, ErrSnapshotMagic
+	}
+	version, err := (*byteReader)(d).ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if version < 1 || version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+	d.version = version
+	n, err := d.decodeNode()
+	if err != nil {
+		return nil, err
+	}
+	expected := d.crc.Sum32()
+	var sum [4]byte
+	if _, err := io.ReadFull(d.r, sum[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if binary.BigEndian.Uint32(sum[:]) != expected {
+		return nil, ErrSnapshotChecksum
+	}
+	return n, nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package data
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func encodeSnapshot(t *testing.T, n *Node) []byte {
+	var b bytes.Buffer
+	if err := NewSnapshotEncoder(&b).Encode(n); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func checkSameNode(t *testing.T, got, expected *Node) {
+	if got.Name() != expected.Name() ||
+		got.Comment() != expected.Comment() ||
+		got.Index() != expected.Index() ||
+		got.flags != expected.flags ||
+		got.nxtChildIdx != expected.nxtChildIdx ||
+		len(got.annotations) != len(expected.annotations) ||
+		got.NumChildren() != expected.NumChildren() {
+		t.Fatalf("node %s not preserved", expected.Name())
+	}
+	for _, ch := range expected.Children() {
+		gch := got.Child(ch.Name())
+		if gch == nil {
+			t.Fatalf("did not find expected child %s", ch.Name())
+		}
+		checkSameNode(t, gch, ch)
+	}
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tree := createBaseTree()
+	tree.Child("Test1").SetComment("a comment")
+	tree.Child("Test2").MarkDeleted(DontClearChildFlags)
+	tree.Child("Test3").MarkDefault()
+	tree.Child("Test4").Child("TestCh1").SetIndex(42)
+	tree.Child("Test5").SetAnnotation("ietf-origin:origin", "or:intended")
+
+	out, err := NewSnapshotDecoder(bytes.NewReader(encodeSnapshot(t, tree))).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSameNode(t, out, tree)
+	if out.Hash() != tree.Hash() {
+		t.Fatal("decoded tree has different hash")
+	}
+}
+
+func TestSnapshotStream(t *testing.T) {
+	var b bytes.Buffer
+	enc := NewSnapshotEncoder(&b)
+	first, second := createBaseTree(), New("other")
+	if err := enc.Encode(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(second); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewSnapshotDecoder(&b)
+	for _, expected := range []*Node{first, second} {
+		n, err := dec.Decode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSameNode(t, n, expected)
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestSnapshotCorrupted(t *testing.T) {
+	snap := encodeSnapshot(t, createBaseTree())
+
+	corrupt := make([]byte, len(snap))
+	copy(corrupt, snap)
+	corrupt[len(corrupt)/2] ^= 0x01
+	_, err := NewSnapshotDecoder(bytes.NewReader(corrupt)).Decode()
+	if err == nil {
+		t.Fatal("corrupted snapshot decoded without error")
+	}
+
+	_, err = NewSnapshotDecoder(bytes.NewReader(snap[:len(snap)-2])).Decode()
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+
+	_, err = NewSnapshotDecoder(bytes.NewBufferString("root {\n}\n")).Decode()
+	if err != ErrSnapshotMagic {
+		t.Fatalf("expected bad magic, got %v", err)
+	}
+}
+
+func TestSnapshotChecksum(t *testing.T) {
+	snap := encodeSnapshot(t, createBaseTree())
+	snap[len(snap)-1] ^= 0xff
+	_, err := NewSnapshotDecoder(bytes.NewReader(snap)).Decode()
+	if err != ErrSnapshotChecksum {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+}
+
+func TestSnapshotDeterministic(t *testing.T) {
+	tree := createBaseTree()
+	expected := encodeSnapshot(t, tree)
+	for i := 0; i < 10; i++ {
+		if !bytes.Equal(encodeSnapshot(t, tree), expected) {
+			t.Fatal("snapshot encoding is not deterministic")
+		}
+	}
+	thawed := encodeSnapshot(t, Freeze(tree).Thaw())
+	if !bytes.Equal(thawed, expected) {
+		t.Fatal("thawed tree encoded differently")
+	}
+}
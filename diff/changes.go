@@ -0,0 +1,212 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package diff
+
+import (
+	"encoding/json"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/utils/pathutil"
+)
+
+// A structured form of the diff, listing each change rather than
+// rendering the trees, for tools that store or display diffs themselves.
+// Changes are reported for leaves, leaf-list values, list entries and
+// presence containers; the leaves of an added or deleted subtree are
+// reported individually. Reordered entries of ordered-by user lists and
+// leaf-lists are always reported, whether or not ShowMoves is given.
+
+type ChangeOperation string
+
+const (
+	ChangeAdded     ChangeOperation = "added"
+	ChangeDeleted   ChangeOperation = "deleted"
+	ChangeUpdated   ChangeOperation = "updated"
+	ChangeReordered ChangeOperation = "reordered"
+)
+
+type Change struct {
+	//Path is the path of the changed node, ending with the key of a list
+	//entry, but not with the value of a leaf or leaf-list
+	Path      []string        `json:"path"`
+	Operation ChangeOperation `json:"operation"`
+	OldValue  *string         `json:"old-value,omitempty"`
+	NewValue  *string         `json:"new-value,omitempty"`
+	//Redacted is set where a secret value, in the path or of the
+	//node itself, has been replaced
+	Redacted bool `json:"redacted"`
+}
+
+type changeWalker struct {
+	opts    *options
+	changes []Change
+}
+
+func statusOperation(s status) ChangeOperation {
+	switch s {
+	case added:
+		return ChangeAdded
+	case deleted:
+		return ChangeDeleted
+	case moved:
+		return ChangeReordered
+	}
+	return ""
+}
+
+func (w *changeWalker) add(
+	path []string,
+	op ChangeOperation,
+	oldval, newval *string,
+	redacted bool,
+) {
+	w.changes = append(w.changes, Change{
+		Path:      path,
+		Operation: op,
+		OldValue:  oldval,
+		NewValue:  newval,
+		Redacted:  redacted,
+	})
+}
+
+func (w *changeWalker) walkChildren(n *Node, path []string, redacted bool) {
+	for _, ch := range n.ChangedChildren() {
+		w.walk(ch, path, redacted)
+	}
+}
+
+// value returns the value of n, redacted if it is a secret.
+func (w *changeWalker) value(n *Node) (*string, bool) {
+	val := n.Name()
+	redact := w.opts.hideSecrets && n.schema.ConfigdExt().Secret
+	val = redactIfRequired(redact, val)
+	return &val, redact
+}
+
+// walkLeaf reports a leaf whose value has been replaced as a single
+// update rather than as the deletion of one value and addition of another.
+func (w *changeWalker) walkLeaf(n *Node, path []string, redacted bool) {
+	if _, isEmpty := n.schema.Type().(schema.Empty); isEmpty {
+		if op := statusOperation(n.getStatus()); op != "" {
+			w.add(path, op, nil, nil, redacted)
+		}
+		return
+	}
+	var oldval, newval *string
+	for _, ch := range n.ChangedChildren() {
+		switch ch.getStatus() {
+		case added:
+			val, redact := w.value(ch)
+			newval, redacted = val, redacted || redact
+		case deleted:
+			val, redact := w.value(ch)
+			oldval, redacted = val, redacted || redact
+		}
+	}
+	switch {
+	case oldval != nil && newval != nil:
+		w.add(path, ChangeUpdated, oldval, newval, redacted)
+	case newval != nil:
+		w.add(path, ChangeAdded, nil, newval, redacted)
+	case oldval != nil:
+		w.add(path, ChangeDeleted, oldval, nil, redacted)
+	}
+}
+
+func (w *changeWalker) walkLeafList(n *Node, path []string, redacted bool) {
+	for _, ch := range n.ChangedChildren() {
+		op := statusOperation(ch.getStatus())
+		if op == "" {
+			continue
+		}
+		val, redact := w.value(ch)
+		if op == ChangeDeleted {
+			w.add(path, op, val, nil, redacted || redact)
+		} else {
+			w.add(path, op, nil, val, redacted || redact)
+		}
+	}
+}
+
+// step returns the path of n, given that of its parent, and whether it
+// includes a redacted list key.
+func (w *changeWalker) step(
+	n *Node,
+	path []string,
+	redacted bool,
+) ([]string, bool) {
+	switch sch := n.schema.(type) {
+	case schema.Container, schema.List, schema.Leaf, schema.LeafList:
+		return pathutil.CopyAppend(path, n.Name()), redacted
+	case schema.ListEntry:
+		keynode := sch.SchemaChild(sch.Keys()[0])
+		redacted = redacted ||
+			(w.opts.hideSecrets && keynode.ConfigdExt().Secret)
+		return pathutil.CopyAppend(path,
+			redactIfRequired(redacted, n.Name())), redacted
+	}
+	return path, redacted
+}
+
+func (w *changeWalker) walk(n *Node, path []string, redacted bool) {
+	path, redacted = w.step(n, path, redacted)
+	switch n.schema.(type) {
+	case schema.Container:
+		if n.schema.HasPresence() {
+			if op := statusOperation(n.getStatus()); op != "" {
+				w.add(path, op, nil, nil, redacted)
+			}
+		}
+		w.walkChildren(n, path, redacted)
+	case schema.ListEntry:
+		if op := statusOperation(n.getStatus()); op != "" {
+			w.add(path, op, nil, nil, redacted)
+		}
+		w.walkChildren(n, path, redacted)
+	case schema.Leaf:
+		w.walkLeaf(n, path, redacted)
+	case schema.LeafList:
+		w.walkLeafList(n, path, redacted)
+	case schema.List:
+		w.walkChildren(n, path, redacted)
+	case schema.Tree:
+		w.walkChildren(n, path, redacted)
+	}
+}
+
+// Changes returns the changes in the subtree of n, in the order they
+// are shown by Serialize, each with its full path from the root.
+func (n *Node) Changes(options ...Option) []Change {
+	w := &changeWalker{
+		opts:    getOptions(options...),
+		changes: make([]Change, 0),
+	}
+	if n == nil {
+		return w.changes
+	}
+	if _, ok := n.schema.(schema.LeafValue); ok {
+		n = n.parent
+	}
+	n = n.withMoves()
+	var path []string
+	redacted := false
+	for _, p := range n.ancestors() {
+		path, redacted = w.step(p, path, redacted)
+	}
+	w.walk(n, path, redacted)
+	return w.changes
+}
+
+// SerializeJSON returns the changes in the subtree of n as a JSON
+// document of the form {"changes": [...]}.
+func (n *Node) SerializeJSON(options ...Option) string {
+	doc := struct {
+		Changes []Change `json:"changes"`
+	}{
+		Changes: n.Changes(options...),
+	}
+	buf, _ := json.Marshal(&doc)
+	return string(buf)
+}
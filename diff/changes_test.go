@@ -0,0 +1,163 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package diff_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/danos/config/diff"
+	"github.com/danos/config/load"
+	. "github.com/danos/config/testutils"
+	"github.com/danos/utils/pathutil"
+)
+
+const changesSchema = `
+container testCont {
+	leaf flag {
+		type empty;
+	}
+	leaf name {
+		type string;
+	}
+	container pres {
+		presence "Presence container";
+	}
+	list rule {
+		key id;
+		leaf id {
+			type string;
+		}
+		leaf action {
+			type string;
+		}
+	}
+	list secret-list {
+		key list-key;
+		leaf list-key {
+			type string;
+			configd:secret true;
+		}
+		leaf data {
+			type string;
+		}
+	}
+	leaf-list tags {
+		type string;
+		ordered-by user;
+	}
+}`
+
+var changesOldCfg = Cont("testCont",
+	EmptyLeaf("flag"),
+	Leaf("name", "One"),
+	List("rule",
+		ListEntry("A",
+			Leaf("action", "drop"))),
+	List("secret-list",
+		ListEntry("s1",
+			Leaf("data", "x"))),
+	LeafList("tags",
+		LeafListEntry("a"),
+		LeafListEntry("b")))
+
+var changesNewCfg = Cont("testCont",
+	Leaf("name", "Two"),
+	Cont("pres"),
+	List("rule",
+		ListEntry("A"),
+		ListEntry("B",
+			Leaf("action", "accept"))),
+	List("secret-list",
+		ListEntry("s2",
+			Leaf("data", "x"))),
+	LeafList("tags",
+		LeafListEntry("b"),
+		LeafListEntry("a")))
+
+func getDiffNode(t *testing.T, oldCfg, newCfg, schema string) *diff.Node {
+	sch := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema))
+	st, err := GetConfigSchema(sch.Bytes())
+	if err != nil {
+		t.Fatalf("Unable to get schema tree: %s", err.Error())
+	}
+	old, err, _ := load.LoadString("oldCfg", oldCfg, st)
+	if err != nil {
+		t.Fatalf("Unable to load oldCfg: %s", err.Error())
+	}
+	new, err, _ := load.LoadString("newCfg", newCfg, st)
+	if err != nil {
+		t.Fatalf("Unable to load newCfg: %s", err.Error())
+	}
+	return diff.NewNode(new, old, st, nil)
+}
+
+func checkChangesJSON(t *testing.T, expect, actual string) {
+	t.Helper()
+	if actual != expect {
+		t.Errorf("Unexpected changes\n   expect=%s\n   actual=%s",
+			expect, actual)
+	}
+}
+
+func TestChangesJSON(t *testing.T) {
+	dtree := getDiffNode(t, changesOldCfg, changesNewCfg, changesSchema)
+
+	expect := `{"changes":[` +
+		`{"path":["testCont","flag"],"operation":"deleted","redacted":false},` +
+		`{"path":["testCont","name"],"operation":"updated",` +
+		`"old-value":"One","new-value":"Two","redacted":false},` +
+		`{"path":["testCont","pres"],"operation":"added","redacted":false},` +
+		`{"path":["testCont","rule","A","action"],"operation":"deleted",` +
+		`"old-value":"drop","redacted":false},` +
+		`{"path":["testCont","rule","B"],"operation":"added","redacted":false},` +
+		`{"path":["testCont","rule","B","action"],"operation":"added",` +
+		`"new-value":"accept","redacted":false},` +
+		`{"path":["testCont","secret-list","********"],` +
+		`"operation":"deleted","redacted":true},` +
+		`{"path":["testCont","secret-list","********","data"],` +
+		`"operation":"deleted","old-value":"x","redacted":true},` +
+		`{"path":["testCont","secret-list","********"],` +
+		`"operation":"added","redacted":true},` +
+		`{"path":["testCont","secret-list","********","data"],` +
+		`"operation":"added","new-value":"x","redacted":true},` +
+		`{"path":["testCont","tags"],"operation":"reordered",` +
+		`"new-value":"b","redacted":false}]}`
+	checkChangesJSON(t, expect, dtree.SerializeJSON(diff.HideSecrets(true)))
+}
+
+func TestChangesShowSecrets(t *testing.T) {
+	dtree := getDiffNode(t, changesOldCfg, changesNewCfg, changesSchema)
+	dtree = dtree.Descendant(pathutil.Makepath("testCont/secret-list/s2"))
+
+	expect := `{"changes":[` +
+		`{"path":["testCont","secret-list","s2"],` +
+		`"operation":"added","redacted":false},` +
+		`{"path":["testCont","secret-list","s2","data"],` +
+		`"operation":"added","new-value":"x","redacted":false}]}`
+	checkChangesJSON(t, expect, dtree.SerializeJSON())
+}
+
+func TestChangesNone(t *testing.T) {
+	dtree := getDiffNode(t, changesOldCfg, changesOldCfg, changesSchema)
+	checkChangesJSON(t, `{"changes":[]}`, dtree.SerializeJSON())
+
+	var nilNode *diff.Node
+	if changes := nilNode.Changes(); len(changes) != 0 {
+		t.Errorf("Unexpected changes for nil node: %v", changes)
+	}
+}
+
+// Reorders are reported without ShowMoves, and for a subtree too
+func TestChangesReorderedSubtree(t *testing.T) {
+	dtree := getDiffNode(t, changesOldCfg, changesNewCfg, changesSchema)
+	dtree = dtree.Descendant(pathutil.Makepath("testCont/tags"))
+
+	expect := `{"changes":[` +
+		`{"path":["testCont","tags"],"operation":"reordered",` +
+		`"new-value":"b","redacted":false}]}`
+	checkChangesJSON(t, expect, dtree.SerializeJSON())
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package diff
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/config/union"
+	"github.com/danos/utils/pathutil"
+)
+
+// The diff as a script of set and delete commands that turns the old tree
+// into the new one. All deletes are written before any set, so nodes of a
+// case being replaced are gone before those of the new case are created.
+//
+// As set appends to an ordered-by user list or leaf-list, entries can't be
+// moved in place. Those from the first one out of position onwards are
+// deleted and set again in their new order.
+
+type commandWriter struct {
+	opts *options
+	buf  bytes.Buffer
+}
+
+func (w *commandWriter) write(cmd string, path []string) {
+	w.buf.WriteString(cmd)
+	for _, elem := range path {
+		w.buf.WriteByte(' ')
+		w.buf.WriteString(elem)
+	}
+	w.buf.WriteByte('\n')
+}
+
+// step returns the command path of n, given that of its parent.
+func (w *commandWriter) step(n *Node, path []string) []string {
+	switch sch := n.schema.(type) {
+	case schema.Container, schema.List, schema.Leaf, schema.LeafList:
+		return pathutil.CopyAppend(path, n.Name())
+	case schema.ListEntry:
+		keynode := sch.SchemaChild(sch.Keys()[0])
+		hide := w.opts.hideSecrets && keynode.ConfigdExt().Secret
+		return pathutil.CopyAppend(path,
+			union.EscapeAndQuote(redactIfRequired(hide, n.Name())))
+	case schema.LeafValue:
+		hide := w.opts.hideSecrets && n.schema.ConfigdExt().Secret
+		return pathutil.CopyAppend(path,
+			union.EscapeAndQuote(redactIfRequired(hide, n.Name())))
+	}
+	return path
+}
+
+// needsDelete is true for a node that has been removed, or reverted to
+// its default.
+func (n *Node) needsDelete() bool {
+	return n.old != nil && !n.old.Default() &&
+		(n.new == nil || n.new.Default())
+}
+
+// needsSet is true for a node that is to be set; under a fresh node all
+// non-default nodes are.
+func (n *Node) needsSet(fresh bool) bool {
+	return n.new != nil && !n.new.Default() &&
+		(fresh || n.old == nil || n.old.Default())
+}
+
+// liveChildren returns the children in the new tree, in order.
+func (n *Node) liveChildren() []*Node {
+	children := n.new.Children()
+	switch n.schema.OrdBy() {
+	case "user":
+		sort.Sort(data.ByUser(children))
+	default:
+		sort.Sort(data.BySystem(children))
+	}
+	out := make([]*Node, 0, len(children))
+	for _, ch := range children {
+		if ch.Deleted() {
+			continue
+		}
+		if dch := n.buildChild(ch.Name()); dch != nil {
+			out = append(out, dch)
+		}
+	}
+	return out
+}
+
+// reappended returns the names of the children of an ordered-by user list
+// or leaf-list that have to be set again, in order, to be in their new
+// positions: those from the first whose position differs from that among
+// the entries kept from the old tree.
+func (n *Node) reappended() map[string]bool {
+	out := make(map[string]bool)
+	if n.schema.OrdBy() != "user" || n.new == nil || n.old == nil {
+		return out
+	}
+	var kept []string
+	for _, ch := range userOrderedChildren(n.old) {
+		if nch := n.new.Child(ch.Name()); nch != nil && !nch.Deleted() {
+			kept = append(kept, ch.Name())
+		}
+	}
+	live := n.liveChildren()
+	split := 0
+	for split < len(kept) && split < len(live) &&
+		kept[split] == live[split].Name() {
+		split++
+	}
+	for _, ch := range live[split:] {
+		out[ch.Name()] = true
+	}
+	return out
+}
+
+func (w *commandWriter) deletes(n *Node, path []string) {
+	path = w.step(n, path)
+	if n.needsDelete() {
+		w.write("delete", path)
+		return
+	}
+	if n.new == nil || n.old == nil || n.identical() {
+		return
+	}
+	if _, ok := n.schema.(schema.Leaf); ok {
+		//Setting the new value replaces the old one
+		return
+	}
+
+	reappend := n.reappended()
+	for _, ch := range userOrderedChildren(n.old) {
+		if reappend[ch.Name()] && !ch.Default() {
+			w.write("delete", w.step(n.buildChild(ch.Name()), path))
+		}
+	}
+	for _, ch := range n.ChangedChildren() {
+		if reappend[ch.Name()] {
+			continue
+		}
+		w.deletes(ch, path)
+	}
+}
+
+func (w *commandWriter) sets(n *Node, path []string, fresh bool) {
+	path = w.step(n, path)
+	if n.new == nil || n.new.Default() {
+		return
+	}
+	switch n.schema.(type) {
+	case schema.LeafValue:
+		if n.needsSet(fresh) {
+			w.write("set", path)
+		}
+		return
+	case schema.Leaf:
+		if _, isEmpty := n.schema.Type().(schema.Empty); isEmpty {
+			if n.needsSet(fresh) {
+				w.write("set", path)
+			}
+			return
+		}
+	}
+
+	var children []*Node
+	reappend := make(map[string]bool)
+	if !fresh {
+		reappend = n.reappended()
+	}
+	if fresh || len(reappend) > 0 {
+		children = n.liveChildren()
+	} else {
+		children = n.ChangedChildren()
+	}
+	before := w.buf.Len()
+	for _, ch := range children {
+		w.sets(ch, path, fresh || reappend[ch.Name()])
+	}
+	if w.buf.Len() > before || !n.needsSet(fresh) {
+		return
+	}
+	//Nothing below it is set, so create the node itself
+	switch n.schema.(type) {
+	case schema.Container, schema.ListEntry:
+		w.write("set", path)
+	}
+}
+
+// SerializeCommands returns the set and delete commands that turn the
+// old subtree of n into the new one, with full paths from the root.
+// With HideSecrets, secret values and list keys are written as
+// "********", so the script is only fit for display: loading it would
+// set the redacted placeholder rather than the secret.
+func (n *Node) SerializeCommands(options ...Option) string {
+	if n == nil {
+		return ""
+	}
+	w := &commandWriter{opts: getOptions(options...)}
+	var path []string
+	for _, p := range n.ancestors() {
+		path = w.step(p, path)
+	}
+	w.deletes(n, path)
+	w.sets(n, path, false)
+	return w.buf.String()
+}
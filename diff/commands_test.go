@@ -0,0 +1,161 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/danos/config/diff"
+	. "github.com/danos/config/testutils"
+	"github.com/danos/config/testutils/assert"
+	"github.com/danos/utils/pathutil"
+)
+
+const commandsSchema = `
+container testCont {
+	leaf mtu {
+		type uint32;
+		default 1500;
+	}
+	leaf descr {
+		type string;
+	}
+	choice addr {
+		case static {
+			leaf address {
+				type string;
+			}
+		}
+		case dynamic {
+			leaf dhcp {
+				type empty;
+			}
+		}
+	}
+	container pres {
+		presence "Presence container";
+	}
+	list rule {
+		ordered-by user;
+		key id;
+		leaf id {
+			type string;
+		}
+		leaf action {
+			type string;
+		}
+	}
+	leaf-list servers {
+		type string;
+	}
+	leaf-list tags {
+		type string;
+		ordered-by user;
+	}
+	list secret-list {
+		key list-key;
+		leaf list-key {
+			type string;
+			configd:secret true;
+		}
+	}
+}`
+
+var commandsOldCfg = Cont("testCont",
+	Leaf("mtu", "9000"),
+	Leaf("descr", "old"),
+	Leaf("address", "10.0.0.1"),
+	List("rule",
+		ListEntry("A",
+			Leaf("action", "drop")),
+		ListEntry("B",
+			Leaf("action", "accept")),
+		ListEntry("C",
+			Leaf("action", "drop"))),
+	LeafList("servers",
+		LeafListEntry("s1")),
+	LeafList("tags",
+		LeafListEntry("a"),
+		LeafListEntry("b")))
+
+var commandsNewCfg = Cont("testCont",
+	Leaf("descr", "\"new value\""),
+	EmptyLeaf("dhcp"),
+	Cont("pres"),
+	List("rule",
+		ListEntry("A",
+			Leaf("action", "drop")),
+		ListEntry("C",
+			Leaf("action", "drop")),
+		ListEntry("B",
+			Leaf("action", "accept")),
+		ListEntry("D")),
+	LeafList("servers",
+		LeafListEntry("s2")),
+	LeafList("tags",
+		LeafListEntry("a"),
+		LeafListEntry("c")),
+	List("secret-list",
+		ListEntry("s3")))
+
+func TestCommandsScript(t *testing.T) {
+	dtree := getDiffNode(t, commandsOldCfg, commandsNewCfg, commandsSchema)
+
+	expect := `delete testCont address
+delete testCont mtu
+delete testCont rule B
+delete testCont rule C
+delete testCont servers s1
+delete testCont tags b
+set testCont descr "new value"
+set testCont dhcp
+set testCont pres
+set testCont rule C action drop
+set testCont rule B action accept
+set testCont rule D
+set testCont secret-list "********"
+set testCont servers s2
+set testCont tags c
+`
+	actual := dtree.SerializeCommands(diff.HideSecrets(true))
+	assert.CheckStringDivergence(t, expect, actual)
+}
+
+func TestCommandsScriptReverse(t *testing.T) {
+	dtree := getDiffNode(t, commandsNewCfg, commandsOldCfg, commandsSchema)
+
+	expect := `delete testCont dhcp
+delete testCont pres
+delete testCont rule C
+delete testCont rule B
+delete testCont rule D
+delete testCont secret-list
+delete testCont servers s2
+delete testCont tags c
+set testCont address 10.0.0.1
+set testCont descr old
+set testCont mtu 9000
+set testCont rule B action accept
+set testCont rule C action drop
+set testCont servers s1
+set testCont tags b
+`
+	actual := dtree.SerializeCommands()
+	assert.CheckStringDivergence(t, expect, actual)
+}
+
+func TestCommandsScriptDescendant(t *testing.T) {
+	dtree := getDiffNode(t, commandsOldCfg, commandsNewCfg, commandsSchema)
+	dtree = dtree.Descendant(pathutil.Makepath("testCont/secret-list"))
+
+	assert.CheckStringDivergence(t,
+		"set testCont secret-list s3\n", dtree.SerializeCommands())
+}
+
+func TestCommandsScriptNoChanges(t *testing.T) {
+	dtree := getDiffNode(t, commandsOldCfg, commandsOldCfg, commandsSchema)
+
+	assert.CheckStringDivergence(t, "", dtree.SerializeCommands())
+}
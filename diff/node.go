@@ -26,6 +26,7 @@ const (
 	unchanged status = iota
 	added
 	deleted
+	moved
 )
 
 type Node struct {
@@ -33,6 +34,13 @@ type Node struct {
 	old    *data.Node
 	schema schema.Node
 	parent *Node
+	//moves is set when reordered entries of ordered-by user lists
+	//and leaf-lists are reported as moved, rather than deleted and
+	//added again; it is inherited by the children.
+	moves bool
+	//moved caches the names of the reordered children of an
+	//ordered-by user list or leaf-list
+	moved map[string]bool
 }
 
 type ByUser []*Node
@@ -87,6 +95,15 @@ func (n *Node) Parent() *Node {
 	return n.parent
 }
 
+// ancestors returns the ancestors of n, starting from the root.
+func (n *Node) ancestors() []*Node {
+	var out []*Node
+	for p := n.parent; p != nil; p = p.parent {
+		out = append([]*Node{p}, out...)
+	}
+	return out
+}
+
 func (n *Node) Name() string {
 	return n.Data().Name()
 }
@@ -101,6 +118,8 @@ func (n *Node) getStatus() status {
 		return deleted
 	case n.Added():
 		return added
+	case n.Moved():
+		return moved
 	default:
 		return unchanged
 	}
@@ -115,10 +134,99 @@ func (n *Node) Deleted() bool {
 	return n.new == nil && n.old != nil
 }
 
+// identical uses the data node hashes to determine that nothing in
+// the subtree has changed without having to walk it.
+func (n *Node) identical() bool {
+	return n.new != nil && n.old != nil &&
+		!n.new.Deleted() && !n.old.Deleted() &&
+		n.new.Equal(n.old)
+}
+
+// userOrderedChildren returns the children of an ordered-by user node in
+// order, without reindexing them.
+func userOrderedChildren(parent *data.Node) []*data.Node {
+	children := parent.Children()
+	sort.Sort(data.ByUser(children))
+	return children
+}
+
+// movedEntries returns the names of the children of an ordered-by user
+// list or leaf-list that are in both trees but whose relative order has
+// changed. Entries in the longest run keeping their old relative order
+// are unmoved, so deleting or inserting an entry doesn't move the others.
+func (n *Node) movedEntries() map[string]bool {
+	if n.moved != nil {
+		return n.moved
+	}
+	n.moved = make(map[string]bool)
+	if !n.moves || n.schema.OrdBy() != "user" || n.new == nil || n.old == nil {
+		return n.moved
+	}
+	oldPos := make(map[string]int)
+	for i, ch := range userOrderedChildren(n.old) {
+		oldPos[ch.Name()] = i
+	}
+	var names []string
+	var positions []int
+	for _, ch := range userOrderedChildren(n.new) {
+		if pos, ok := oldPos[ch.Name()]; ok && !ch.Deleted() {
+			names = append(names, ch.Name())
+			positions = append(positions, pos)
+		}
+	}
+
+	// Longest increasing subsequence of the old positions; tails[k]
+	// is the index of the smallest end of a subsequence of length k+1.
+	var tails []int
+	prev := make([]int, len(positions))
+	for i, pos := range positions {
+		k := sort.Search(len(tails), func(j int) bool {
+			return positions[tails[j]] >= pos
+		})
+		prev[i] = -1
+		if k > 0 {
+			prev[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	inOrder := make(map[int]bool)
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			inOrder[i] = true
+		}
+	}
+	for i, name := range names {
+		if !inOrder[i] {
+			n.moved[name] = true
+		}
+	}
+	return n.moved
+}
+
+// Moved is true, when moves are reported, for an entry of an ordered-by
+// user list or leaf-list whose position has changed. A moved entry is reported once, at its new
+// position, and is Updated rather than deleted and re-added.
+func (n *Node) Moved() bool {
+	if n.parent == nil || n.new == nil || n.old == nil {
+		return false
+	}
+	return n.parent.movedEntries()[n.Name()]
+}
+
 func (n *Node) Updated() bool {
 	//Updated means we have a child that has changed
 	//it turns out that this accounts for all 3 cases
 	//previously handled.
+	if n.Moved() {
+		return true
+	}
+	if n.identical() {
+		return false
+	}
 
 	//technically this is deleted, but defaults are special
 	//and need to be considered updated
@@ -270,29 +378,97 @@ func (n *Node) traverseDiffChildrenUser(
 	}
 }
 
+// traverseDiffChildrenMoved is traverseDiffChildrenUser when moves are
+// reported.
+//
+// We first traverse the list of old nodes, skipping any that have moved as
+// they are reported at their new position.
+//
+// We then traverse the list of new nodes, reporting those that are Added or
+// Moved.  If the node is only marked as updated we ignore it as it will
+// already be in the list of old nodes as Updated.
+func (n *Node) traverseDiffChildrenMoved(
+	fn func(*Node),
+	skip func(*Node) bool,
+) {
+	//Only the entries whose relative order changed
+	//are shown as moved.
+	skipFn := func(ch *Node) bool {
+		return skip != nil && skip(ch)
+	}
+
+	moved := n.movedEntries()
+	var dch *Node
+	for _, ch := range n.getSortedChildren(n.old) {
+		sch := n.schema.SchemaChild(ch.Name())
+		if sch == nil || moved[ch.Name()] {
+			continue
+		}
+		new := n.new.Child(ch.Name())
+		if new != nil && new.Deleted() {
+			new = nil
+		}
+		dch = NewNode(new, ch, sch, n)
+		if skipFn(dch) {
+			continue
+		}
+		fn(dch)
+	}
+	for _, ch := range n.getSortedChildren(n.new) {
+		sch := n.schema.SchemaChild(ch.Name())
+		if sch == nil {
+			continue
+		}
+		dch = NewNode(ch, n.old.Child(ch.Name()), sch, n)
+		if skipFn(dch) || !(dch.Added() || dch.Moved()) {
+			continue
+		}
+		fn(dch)
+	}
+}
+
 func (n *Node) Children() []*Node {
-	return n.sortChildren(n.children())
+	return n.sortChildren(n.children(nil))
 }
 
 func (n *Node) UnsortedChildren() []*Node {
-	return n.children()
+	return n.children(nil)
+}
+
+// ChangedChildren returns the sorted children, leaving out any whose
+// subtree is identical in the old and new trees. Unlike Children the
+// unchanged subtrees are never walked, which makes it much cheaper for
+// callers that are only interested in the differences.
+func (n *Node) ChangedChildren() []*Node {
+	if n.identical() {
+		return []*Node{}
+	}
+	return n.sortChildren(n.children(func(ch *Node) bool {
+		return ch.identical() && !ch.Moved()
+	}))
 }
 
-func (n *Node) children() []*Node {
+func (n *Node) children(skip func(*Node) bool) []*Node {
 
 	out := make([]*Node, 0)
 	travFn := func(n *Node) {
 		out = append(out, n)
 	}
-	if n.schema.OrdBy() == "user" {
-		n.traverseDiffChildrenUser(
+	switch {
+	case n.schema.OrdBy() != "user":
+		n.traverseDiffChildren(
 			travFn,
-			nil,
+			skip,
 		)
-	} else {
-		n.traverseDiffChildren(
+	case n.moves:
+		n.traverseDiffChildrenMoved(
+			travFn,
+			skip,
+		)
+	default:
+		n.traverseDiffChildrenUser(
 			travFn,
-			nil,
+			skip,
 		)
 	}
 	return out
@@ -581,6 +757,9 @@ func (n *Node) Serialize(ctxdiff bool, options ...Option) string {
 	if n == nil {
 		return ""
 	}
+	if opts.showMoves {
+		n = n.withMoves()
+	}
 	var buf bytes.Buffer
 	n.serialize(&buf, nil, ctxdiff, opts, 0)
 	return buf.String()
@@ -613,11 +792,27 @@ func printStatus(w io.Writer, n *Node) {
 		fmt.Fprint(w, "+")
 	case deleted:
 		fmt.Fprint(w, "-")
+	case moved:
+		fmt.Fprint(w, ">")
 	default:
 		fmt.Fprint(w, " ")
 	}
 }
 
+// withMoves returns a copy of n, and of its ancestors, that reports moves
+func (n *Node) withMoves() *Node {
+	if n == nil || n.moves {
+		return n
+	}
+	return &Node{
+		new:    n.new,
+		old:    n.old,
+		schema: n.schema,
+		parent: n.parent.withMoves(),
+		moves:  true,
+	}
+}
+
 func NewNode(new, old *data.Node, sch schema.Node, parent *Node) *Node {
 	switch {
 	case sch == nil:
@@ -630,6 +825,7 @@ func NewNode(new, old *data.Node, sch schema.Node, parent *Node) *Node {
 		old:    old,
 		schema: sch,
 		parent: parent,
+		moves:  parent != nil && parent.moves,
 	}
 }
 
@@ -7,6 +7,7 @@ package diff
 
 type options struct {
 	hideSecrets bool
+	showMoves   bool
 }
 
 type Option func(*options)
@@ -17,6 +18,15 @@ func HideSecrets(hide bool) Option {
 	}
 }
 
+// ShowMoves reports the entries of ordered-by user lists and leaf-lists
+// whose position has changed as moved ('>'), rather than as deleted and
+// added again.
+func ShowMoves(show bool) Option {
+	return func(opts *options) {
+		opts.showMoves = show
+	}
+}
+
 func getOptions(ops ...Option) *options {
 	var opts options
 	for _, opt := range ops {
@@ -44,13 +44,22 @@ module test-configd-diff {
 }
 `
 
-func compare(t1, t2 *data.Node, st schema.Tree, spath string) string {
+func compare(
+	t1, t2 *data.Node,
+	st schema.Tree,
+	spath string,
+	options ...diff.Option,
+) string {
 	dtree := diff.NewNode(t1, t2, st, nil)
 	dtree = dtree.Descendant(pathutil.Makepath(spath))
-	return fmt.Sprintf("%s\n", dtree.Serialize(false))
+	return fmt.Sprintf("%s\n", dtree.Serialize(false, options...))
 }
 
-func getDiff(t *testing.T, oldCfg, newCfg, schema string) string {
+func getDiff(
+	t *testing.T,
+	oldCfg, newCfg, schema string,
+	options ...diff.Option,
+) string {
 	sch := bytes.NewBufferString(fmt.Sprintf(schemaTemplate, schema))
 	st, err := GetConfigSchema(sch.Bytes())
 	if err != nil {
@@ -70,7 +79,7 @@ func getDiff(t *testing.T, oldCfg, newCfg, schema string) string {
 		return ""
 	}
 
-	return compare(new, old, st, "")
+	return compare(new, old, st, "", options...)
 }
 
 const ordByUserListSchema = `
@@ -293,3 +302,77 @@ func TestOrdByUserLeafListSwapEntry(t *testing.T) {
 
 	assert.CheckStringDivergence(t, expect, actual)
 }
+
+// With moves shown, only the entries whose position relative to the
+// other entries has changed are shown as moved ('>'), at their new
+// position; entries that only shift because of an insertion or deletion
+// are unchanged.
+
+func TestOrdByUserListDeleteFirstEntryShowMoves(t *testing.T) {
+	expect := FormatAsDiff(
+		Cont("testCont",
+			List("aList",
+				Rem(ListEntry("A",
+					Leaf("aLeaf", "One"))),
+				ListEntry("B",
+					Leaf("aLeaf", "One")))))
+
+	actual := getDiff(t, A1B1_Cfg, B1_Cfg, ordByUserListSchema,
+		diff.ShowMoves(true))
+
+	assert.CheckStringDivergence(t, expect, actual)
+}
+
+func TestOrdByUserListSwapEntryShowMoves(t *testing.T) {
+	expect := FormatAsDiff(
+		Cont("testCont",
+			List("aList",
+				Mov(ListEntry("B",
+					Leaf("aLeaf", "One"))),
+				ListEntry("A",
+					Leaf("aLeaf", "One")))))
+
+	actual := getDiff(t, A1B1_Cfg, B1A1_Cfg, ordByUserListSchema,
+		diff.ShowMoves(true))
+
+	assert.CheckStringDivergence(t, expect, actual)
+}
+
+func TestOrdByUserLeafListSwapEntryShowMoves(t *testing.T) {
+	expect := FormatAsDiff(
+		Cont("testCont",
+			LeafList("aLeafList",
+				Mov(LeafListEntry("Two")),
+				LeafListEntry("One"))))
+
+	actual := getDiff(t, LL_12_Cfg, LL_21_Cfg, ordByUserLeafListSchema,
+		diff.ShowMoves(true))
+
+	assert.CheckStringDivergence(t, expect, actual)
+}
+
+var LL_123_Cfg = Cont("testCont",
+	LeafList("aLeafList",
+		LeafListEntry("One"),
+		LeafListEntry("Two"),
+		LeafListEntry("Three")))
+
+var LL_231_Cfg = Cont("testCont",
+	LeafList("aLeafList",
+		LeafListEntry("Two"),
+		LeafListEntry("Three"),
+		LeafListEntry("One")))
+
+func TestOrdByUserLeafListMoveFirstToLastShowMoves(t *testing.T) {
+	expect := FormatAsDiff(
+		Cont("testCont",
+			LeafList("aLeafList",
+				LeafListEntry("Two"),
+				LeafListEntry("Three"),
+				Mov(LeafListEntry("One")))))
+
+	actual := getDiff(t, LL_123_Cfg, LL_231_Cfg, ordByUserLeafListSchema,
+		diff.ShowMoves(true))
+
+	assert.CheckStringDivergence(t, expect, actual)
+}
@@ -193,3 +193,18 @@ func TestLoadNoTrailingNewline(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadNonExistentPathSuggestion(t *testing.T) {
+
+	const testConfig = `
+		testcont {
+			testlef stuff
+		}
+	`
+
+	warnings := loadTestConfig(t, mergeOrLoadSchema, testConfig)
+
+	expErrors := assert.NewExpectedMessages("Did you mean: testleaf?")
+
+	checkWarnings(t, warnings, expErrors)
+}
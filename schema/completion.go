@@ -0,0 +1,212 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	yang "github.com/danos/yang/schema"
+)
+
+// Structured completion, for front ends that render their own completion
+// and validation hints. Unlike the help maps, a Completion keeps the
+// node's kind and its type's restrictions.
+
+type TypeInfo struct {
+	//Name is the built-in YANG type, eg uint32 or enumeration
+	Name        string
+	Ranges      []string
+	Lengths     []string
+	Patterns    []string
+	PatternHelp []string
+	//Values are the enumerations, identities or booleans, with their help
+	Values map[string]string
+	//Members are the types of a union
+	Members []*TypeInfo
+}
+
+type Completion struct {
+	Name string
+	//Kind is the YANG statement, eg container or leaf-list
+	Kind string
+	Help string
+	//Type is the type of a leaf or leaf-list, or of a list's key
+	Type    *TypeInfo
+	Keys    []string
+	Default []string
+	//Allowed is the configd:allowed script listing the possible values,
+	//see AllowedValues
+	Allowed   string
+	Mandatory bool
+	Presence  bool
+	Secret    bool
+
+	//Exists, Instances and Candidates describe the data, and are set by
+	//the caller. Instances are the values of a leaf or leaf-list, or a
+	//list's keys. Candidates are values the leaf or leaf-list may take,
+	//eg the existing targets of a leafref.
+	Exists     bool
+	Instances  []string
+	Candidates []string
+}
+
+func nodeKind(n yang.Node) string {
+	switch n.(type) {
+	case Tree:
+		return "tree"
+	case Container:
+		return "container"
+	case List:
+		return "list"
+	case ListEntry:
+		return "list-entry"
+	case Leaf:
+		return "leaf"
+	case LeafList:
+		return "leaf-list"
+	case LeafValue:
+		return "value"
+	case Choice:
+		return "choice"
+	case Case:
+		return "case"
+	default:
+		return ""
+	}
+}
+
+// GetTypeInfo describes the type t of the node p
+func GetTypeInfo(p yang.Node, t yang.Type) *TypeInfo {
+	info := &TypeInfo{}
+	switch v := t.(type) {
+	case Binary:
+		info.Name = "binary"
+	case Bits:
+		info.Name = "bits"
+	case Boolean:
+		info.Name = "boolean"
+		info.Values = getTypePatternHelp(p, t)
+	case Decimal64:
+		info.Name = "decimal64"
+		for _, rb := range v.Rbs() {
+			info.Ranges = append(info.Ranges, rb.String())
+		}
+	case Empty:
+		info.Name = "empty"
+	case Enumeration:
+		info.Name = "enumeration"
+		info.Values = v.getHelpMap()
+	case Identityref:
+		info.Name = "identityref"
+		info.Values = v.getHelpMap(p.Module())
+	case InstanceId:
+		info.Name = "instance-identifier"
+	case Integer:
+		info.Name = fmt.Sprintf("int%d", v.BitWidth())
+		for _, rb := range v.Rbs() {
+			info.Ranges = append(info.Ranges, rb.String())
+		}
+	case Uinteger:
+		info.Name = fmt.Sprintf("uint%d", v.BitWidth())
+		for _, rb := range v.Rbs() {
+			info.Ranges = append(info.Ranges, rb.String())
+		}
+	case Leafref:
+		info.Name = "leafref"
+		info.PatternHelp = patternHelp(v.ConfigdExt())
+	case String:
+		info.Name = "string"
+		for _, lb := range v.Lbs() {
+			info.Lengths = append(info.Lengths, lb.String())
+		}
+		for _, pats := range v.Pats() {
+			for _, pat := range pats {
+				info.Patterns = append(info.Patterns, pat.String())
+			}
+		}
+		info.PatternHelp = patternHelp(v.ConfigdExt())
+	case Union:
+		info.Name = "union"
+		for _, typ := range v.Typs() {
+			info.Members = append(info.Members, GetTypeInfo(p, typ))
+		}
+	}
+	return info
+}
+
+func patternHelp(ext *ConfigdExt) []string {
+	var out []string
+	for _, ph := range append(ext.PatternHelp, ext.OpdPatternHelp...) {
+		if ph != "" {
+			out = append(out, ph)
+		}
+	}
+	return out
+}
+
+// typedNode returns the node whose type gives the values of n: the node
+// itself for a leaf or leaf-list, or the key of a list.
+func typedNode(n yang.Node) yang.Node {
+	switch v := n.(type) {
+	case Leaf, LeafList:
+		return n
+	case List:
+		entry, ok := v.Child("Dummy").(ListEntry)
+		if !ok {
+			return nil
+		}
+		return entry.Child(entry.Keys()[0])
+	default:
+		return nil
+	}
+}
+
+type hasMandatory interface {
+	Mandatory() bool
+}
+
+// GetCompletion describes the schema node n. The data dependent fields
+// are left for the caller to fill in.
+func GetCompletion(n Node) *Completion {
+	c := &Completion{
+		Name:     n.Name(),
+		Kind:     nodeKind(n),
+		Help:     GetHelp(n),
+		Default:  n.DefaultChildNames(),
+		Presence: n.HasPresence(),
+	}
+	if m, ok := n.(hasMandatory); ok {
+		c.Mandatory = m.Mandatory()
+	}
+	if l, ok := n.(List); ok {
+		c.Keys = l.Keys()
+	}
+	typed := typedNode(n)
+	if typed == nil {
+		return c
+	}
+	c.Type = GetTypeInfo(typed, typed.Type())
+	ext := typed.(hasExtensions).ConfigdExt()
+	c.Secret = ext.Secret
+	c.Allowed = ext.Allowed
+	if c.Allowed == "" {
+		c.Allowed = typed.Type().(hasExtensions).ConfigdExt().Allowed
+	}
+	return c
+}
+
+// AllowedValues runs the configd:allowed script for the node at path,
+// returning the values it lists.
+func AllowedValues(sid string, path []string, script string) ([]string, error) {
+	if script == "" {
+		return nil, nil
+	}
+	out, err := execExtension(sid, "allowed", path, script)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
@@ -86,6 +86,32 @@ type ComponentManager interface {
 	) (*rfc7951data.Tree, error)
 }
 
+// OriginStateGetter is implemented by the component managers able to
+// annotate the state they return with its NMDA origin.
+type OriginStateGetter interface {
+	ComponentGetStateWithOrigin(
+		ModelSet,
+		datanode.DataNode,
+		*rfc7951data.Tree,
+		StateLogger,
+	) (*rfc7951data.Tree, error)
+}
+
+// ComponentGetStateWithOrigin returns the state from cm with its origin
+// annotated, or without any origin if cm isn't an OriginStateGetter.
+func ComponentGetStateWithOrigin(
+	cm ComponentManager,
+	m ModelSet,
+	dn datanode.DataNode,
+	ft *rfc7951data.Tree,
+	logger StateLogger,
+) (*rfc7951data.Tree, error) {
+	if og, ok := cm.(OriginStateGetter); ok {
+		return og.ComponentGetStateWithOrigin(m, dn, ft, logger)
+	}
+	return cm.ComponentGetState(m, dn, ft, logger)
+}
+
 type compMgr struct {
 	OperationsManager
 	ServiceManager
@@ -96,6 +122,7 @@ type compMgr struct {
 var _ OperationsManager = (*compMgr)(nil)
 var _ ServiceManager = (*compMgr)(nil)
 var _ ComponentManager = (*compMgr)(nil)
+var _ OriginStateGetter = (*compMgr)(nil)
 
 func NewCompMgr(
 	opsMgr OperationsManager,
@@ -301,6 +328,28 @@ func (cm *compMgr) ComponentGetState(
 	ft *rfc7951data.Tree,
 	logger StateLogger,
 ) (*rfc7951data.Tree, error) {
+	return cm.getState(m, dn, ft, logger, false)
+}
+
+// ComponentGetStateWithOrigin is ComponentGetState with the NMDA origin
+// of the nodes annotated: the config in ft is intended, the state
+// returned by the components is learned.
+func (cm *compMgr) ComponentGetStateWithOrigin(
+	m ModelSet,
+	dn datanode.DataNode,
+	ft *rfc7951data.Tree,
+	logger StateLogger,
+) (*rfc7951data.Tree, error) {
+	return cm.getState(m, dn, ft, logger, true)
+}
+
+func (cm *compMgr) getState(
+	m ModelSet,
+	dn datanode.DataNode,
+	ft *rfc7951data.Tree,
+	logger StateLogger,
+	withOrigin bool,
+) (*rfc7951data.Tree, error) {
 
 	if err := cm.Dial(); err != nil {
 		return nil,
@@ -310,6 +359,10 @@ func (cm *compMgr) ComponentGetState(
 	}
 
 	allState := newRFC7951Merger(m, ft)
+	if withOrigin {
+		allState.annotateOrigin(OriginIntended)
+		allState.origin = OriginLearned
+	}
 
 	for _, model := range cm.listActiveModels(m, dn) {
 		compStartTime := time.Now()
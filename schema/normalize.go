@@ -92,11 +92,12 @@ func NormalizePath(st Node, ps []string) ([]string, error) {
 	var sn Node = st
 
 	for i, v := range ps {
+		parent := sn
 		sn = sn.SchemaChild(v)
 		if sn == nil {
 			cerr := mgmterror.NewUnknownElementApplicationError(v)
 			cerr.Path = pathutil.Pathstr(ps[:i])
-			return nil, cerr
+			return nil, WithSuggestions(cerr, SuggestChild(parent, v))
 		}
 		if newV, err := normalizeValue(sn, v); err != nil {
 			cerr := mgmterror.NewInvalidValueApplicationError()
@@ -0,0 +1,105 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package schema
+
+import (
+	"strings"
+
+	"github.com/danos/encoding/rfc7951/data"
+	yang "github.com/danos/yang/schema"
+)
+
+// NMDA (RFC 8342) origin of a node's value, reported using the
+// ietf-origin:origin metadata annotation. A node without the annotation
+// has its parent's origin.
+
+type Origin string
+
+const (
+	OriginIntended Origin = "ietf-origin:intended"
+	OriginDefault  Origin = "ietf-origin:default"
+	OriginSystem   Origin = "ietf-origin:system"
+	OriginLearned  Origin = "ietf-origin:learned"
+	OriginUnknown  Origin = "ietf-origin:unknown"
+)
+
+const (
+	OriginModule     = "ietf-origin"
+	OriginNamespace  = "urn:ietf:params:xml:ns:yang:ietf-origin"
+	OriginAnnotation = "ietf-origin:origin"
+)
+
+// isMetadata is true for the RFC 7952 metadata members of an object
+func isMetadata(member string) bool {
+	return strings.HasPrefix(member, "@")
+}
+
+func isScalar(v *data.Value) bool {
+	return v.Perform(
+		func(*data.Array) bool { return false },
+		func(*data.Object) bool { return false },
+		func(*data.Value) bool { return true },
+	).(bool)
+}
+
+// originMetadata returns the metadata object anns, which may be nil,
+// with the origin added.
+func originMetadata(anns *data.Value, origin Origin) *data.Value {
+	obj := data.ObjectWith()
+	if anns != nil {
+		if o := anns.ToObject(); o != nil {
+			obj = o
+		}
+	}
+	return data.ValueNew(obj.Transform(func(out *data.TObject) {
+		out = out.Assoc(OriginAnnotation, data.ValueNew(string(origin)))
+	}))
+}
+
+// annotateObject adds the origin to the object's "@" metadata member
+func annotateObject(obj *data.Object, origin Origin) *data.Value {
+	return data.ValueNew(obj.Transform(func(out *data.TObject) {
+		out = out.Assoc("@", originMetadata(obj.At("@"), origin))
+	}))
+}
+
+// annotateMember adds the origin of the member key, with value val, of
+// the object being built. Containers and list entries carry their own
+// metadata; leaves and leaf-lists have a sibling "@key" member.
+func annotateMember(
+	out *data.TObject,
+	sn yang.Node,
+	key string,
+	val *data.Value,
+	origin Origin,
+) *data.TObject {
+	return val.Perform(
+		func(arr *data.Array) *data.TObject {
+			if _, isList := sn.(yang.List); isList {
+				return out.Assoc(key, data.ValueNew(
+					arr.Transform(func(entries *data.TArray) {
+						arr.Range(func(i int, v *data.Value) {
+							if obj := v.ToObject(); obj != nil {
+								entries = entries.Assoc(i,
+									annotateObject(obj, origin))
+							}
+						})
+					})))
+			}
+			anns := data.ArrayWith().Transform(func(anns *data.TArray) {
+				arr.Range(func(i int, v *data.Value) {
+					anns = anns.Append(originMetadata(nil, origin))
+				})
+			})
+			return out.Assoc("@"+key, data.ValueNew(anns))
+		},
+		func(obj *data.Object) *data.TObject {
+			return out.Assoc(key, annotateObject(obj, origin))
+		},
+		func(v *data.Value) *data.TObject {
+			return out.Assoc("@"+key, originMetadata(nil, origin))
+		},
+	).(*data.TObject)
+}
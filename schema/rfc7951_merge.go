@@ -14,6 +14,9 @@ import (
 type rfc7951Merger struct {
 	schema schema.ModelSet
 	tree   *data.Tree
+	//origin, if set, is the origin of the nodes added or
+	//changed by a merge
+	origin Origin
 }
 
 func newRFC7951Merger(sch schema.ModelSet, tree *data.Tree) *rfc7951Merger {
@@ -27,6 +30,22 @@ func (m *rfc7951Merger) getTree() *data.Tree {
 	return m.tree
 }
 
+// annotateOrigin sets the origin of the top level nodes of the tree
+func (m *rfc7951Merger) annotateOrigin(origin Origin) {
+	obj := m.tree.Root().ToObject()
+	if obj == nil {
+		return
+	}
+	m.tree = data.TreeFromObject(obj.Transform(func(out *data.TObject) {
+		obj.Range(func(key string, val *data.Value) {
+			if !isMetadata(key) {
+				out = annotateMember(out, m.childSchema(m.schema, key),
+					key, val, origin)
+			}
+		})
+	}))
+}
+
 func (m *rfc7951Merger) merge(other *data.Tree) {
 	m.tree = data.TreeFromObject(
 		m.mergeInternal(m.schema, m.tree.Root(), other.Root()).
@@ -60,14 +79,22 @@ func (m *rfc7951Merger) mergeObject(
 			obj.Range(func(key string, val *data.Value) {
 				if n.Contains(key) {
 					sChild := sn.Child(m.parseKey(key))
-					out = out.Assoc(key,
-						m.mergeInternal(sChild,
-							val, n.At(key)))
+					merged := m.mergeInternal(sChild, val, n.At(key))
+					out = out.Assoc(key, merged)
+					if m.origin != "" && isScalar(val) &&
+						merged.String() != val.String() {
+						out = annotateMember(out, sChild,
+							key, merged, m.origin)
+					}
 				}
 			})
 			n.Range(func(key string, val *data.Value) {
 				if !obj.Contains(key) {
 					out = out.Assoc(key, val)
+					if m.origin != "" && !isMetadata(key) {
+						out = annotateMember(out,
+							m.childSchema(sn, key), key, val, m.origin)
+					}
 				}
 			})
 		})
@@ -137,6 +164,9 @@ func (m *rfc7951Merger) mergeArrayByKey(
 				val := n.At(i)
 				_, ok := entries[k]
 				if !ok {
+					if obj := val.ToObject(); m.origin != "" && obj != nil {
+						val = annotateObject(obj, m.origin)
+					}
 					out = out.Append(val)
 				}
 			}
@@ -190,3 +220,11 @@ func (m *rfc7951Merger) parseKey(in string) string {
 		return elems[1]
 	}
 }
+
+// childSchema returns the schema for the member key of an object
+func (m *rfc7951Merger) childSchema(sn schema.Node, key string) schema.Node {
+	if sn == nil {
+		return nil
+	}
+	return sn.Child(m.parseKey(key))
+}
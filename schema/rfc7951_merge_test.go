@@ -119,3 +119,81 @@ container testcontainer {
 		t.Fatal("merge updated incorrect element")
 	}
 }
+
+func originOf(obj *data.Object, member string) Origin {
+	anns := obj.At(member)
+	if anns == nil || anns.ToObject() == nil {
+		return ""
+	}
+	origin := anns.ToObject().At(OriginAnnotation)
+	if origin == nil {
+		return ""
+	}
+	return Origin(origin.String())
+}
+
+func TestRFC7951MergeOrigin(t *testing.T) {
+	const testSchema = `
+container testcontainer {
+        list testlist {
+                key nodetag;
+                leaf nodetag {
+                        type string;
+                }
+		leaf testleaf {
+			type string;
+		}
+        }
+}
+`
+	msFull, err := makeSchema(t, testSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := data.TreeFromObject(
+		data.ObjectWith(
+			data.PairNew("test-merge:testcontainer",
+				data.ObjectWith(
+					data.PairNew("testlist", data.ArrayWith(
+						data.ObjectWith(
+							data.PairNew("nodetag", "foo"),
+							data.PairNew("testleaf", "bar"),
+						),
+					))))))
+	state := data.TreeFromObject(
+		data.ObjectWith(
+			data.PairNew("test-merge:testcontainer",
+				data.ObjectWith(
+					data.PairNew("testlist", data.ArrayWith(
+						data.ObjectWith(
+							data.PairNew("nodetag", "foo"),
+							data.PairNew("testleaf", "baz"),
+						),
+						data.ObjectWith(
+							data.PairNew("nodetag", "new"),
+						),
+					))))))
+
+	mrgr := newRFC7951Merger(msFull, config)
+	mrgr.annotateOrigin(OriginIntended)
+	mrgr.origin = OriginLearned
+	mrgr.merge(state)
+	out := mrgr.getTree()
+
+	cont := out.At("/test-merge:testcontainer").ToObject()
+	if origin := originOf(cont, "@"); origin != OriginIntended {
+		t.Errorf("Unexpected container origin: %s", origin)
+	}
+	foo := out.At("/test-merge:testcontainer/testlist[nodetag='foo']").ToObject()
+	if origin := originOf(foo, "@"); origin != "" {
+		t.Errorf("Unexpected configured entry origin: %s", origin)
+	}
+	if origin := originOf(foo, "@testleaf"); origin != OriginLearned {
+		t.Errorf("Unexpected changed leaf origin: %s", origin)
+	}
+	entry := out.At("/test-merge:testcontainer/testlist[nodetag='new']").ToObject()
+	if origin := originOf(entry, "@"); origin != OriginLearned {
+		t.Errorf("Unexpected state entry origin: %s", origin)
+	}
+}
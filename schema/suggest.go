@@ -0,0 +1,161 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package schema
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/natsort"
+	yang "github.com/danos/yang/schema"
+)
+
+// "Did you mean" suggestions for misspelt path elements. Candidates that
+// start with the element are ranked first, as it may be an abbreviation,
+// then those within a small edit distance of it.
+
+const maxSuggestions = 3
+
+// editDistance is the optimal string alignment distance: the number of
+// insertions, deletions, substitutions and transpositions between a and b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minInt(vals ...int) int {
+	out := vals[0]
+	for _, v := range vals[1:] {
+		if v < out {
+			out = v
+		}
+	}
+	return out
+}
+
+// Suggest returns the candidates, best first, that name may be a
+// misspelling or abbreviation of.
+func Suggest(name string, candidates []string) []string {
+	type match struct {
+		name   string
+		prefix bool
+		dist   int
+	}
+	if name == "" {
+		return nil
+	}
+	maxDist := len(name) / 3
+	if maxDist < 1 {
+		maxDist = 1
+	}
+	seen := make(map[string]bool)
+	var matches []match
+	for _, c := range candidates {
+		if c == name || seen[c] {
+			continue
+		}
+		seen[c] = true
+		m := match{
+			name:   c,
+			prefix: len(name) > 1 && strings.HasPrefix(c, name),
+			dist:   editDistance(strings.ToLower(name), strings.ToLower(c)),
+		}
+		if m.prefix || (m.dist <= maxDist && m.dist < len(name)) {
+			matches = append(matches, m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.prefix != b.prefix:
+			return a.prefix
+		case a.dist != b.dist:
+			return a.dist < b.dist
+		}
+		return natsort.Less(a.name, b.name)
+	})
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	out := make([]string, 0, len(matches))
+	for _, m := range matches {
+		out = append(out, m.name)
+	}
+	return out
+}
+
+// ChildNames returns the names of the children that can follow n in a
+// path, looking through choices and cases.
+func ChildNames(n Node) []string {
+	var out []string
+	for _, ch := range n.Children() {
+		if ch.Status() == yang.Obsolete {
+			continue
+		}
+		switch ch.(type) {
+		case Choice, Case:
+			out = append(out, ChildNames(ch.(Node))...)
+		default:
+			out = append(out, ch.Name())
+		}
+	}
+	return out
+}
+
+// SuggestChild returns the children of n that name may be a misspelling
+// or abbreviation of.
+func SuggestChild(n Node, name string) []string {
+	if n == nil {
+		return nil
+	}
+	return Suggest(name, ChildNames(n))
+}
+
+// WithSuggestions adds the suggestions to the message of a management
+// error. Other errors are returned unchanged.
+func WithSuggestions(err error, suggestions []string) error {
+	if len(suggestions) == 0 {
+		return err
+	}
+	var msg *string
+	switch e := err.(type) {
+	case *mgmterror.UnknownElementApplicationError:
+		msg = &e.Message
+	case *mgmterror.InvalidValueApplicationError:
+		msg = &e.Message
+	case *mgmterror.DataMissingError:
+		msg = &e.Message
+	case *mgmterror.OperationFailedApplicationError:
+		msg = &e.Message
+	default:
+		return err
+	}
+	hint := "Did you mean: " + strings.Join(suggestions, ", ") + "?"
+	if *msg != "" {
+		hint = *msg + "\n" + hint
+	}
+	*msg = hint
+	return err
+}
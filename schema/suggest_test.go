@@ -0,0 +1,43 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package schema
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danos/mgmterror"
+)
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{
+		"interfaces", "protocols", "service", "system", "security"}
+	for _, test := range []struct {
+		name   string
+		expect []string
+	}{
+		{"interfcaes", []string{"interfaces"}},
+		{"se", []string{"service", "security"}},
+		{"sytsem", []string{"system"}},
+		{"protocol", []string{"protocols"}},
+		{"policy", []string{}},
+		{"system", []string{}},
+	} {
+		actual := Suggest(test.name, candidates)
+		if !reflect.DeepEqual(actual, test.expect) {
+			t.Errorf("Unexpected suggestions for %s\n   expect=%v\n   actual=%v",
+				test.name, test.expect, actual)
+		}
+	}
+}
+
+func TestWithSuggestions(t *testing.T) {
+	err := mgmterror.NewUnknownElementApplicationError("sytsem")
+	err.Message = "Path is invalid"
+	WithSuggestions(err, []string{"system"})
+	if err.Message != "Path is invalid\nDid you mean: system?" {
+		t.Errorf("Unexpected message: %s", err.Message)
+	}
+}
@@ -10,6 +10,7 @@ package schema
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/danos/mgmterror"
@@ -161,12 +162,15 @@ type Enumeration interface {
 	yang.Enumeration
 	hasExtensions
 	getHelpMap() map[string]string
+	EnumValue(name string) (int64, bool)
+	EnumName(value int64) (string, bool)
 }
 
 type enumeration struct {
 	yang.Enumeration
 	*extensions
 	helpMap map[string]string
+	values  map[string]int64
 }
 
 // Compile time check that the concrete type meets the interface
@@ -213,12 +217,52 @@ func parseEnumHelpMap(p parse.Node, base yang.Type) map[string]string {
 	return helpMap
 }
 
+// parseEnumValues returns the integer value of each enum, either given
+// by its value statement or one more than the highest value before it.
+func parseEnumValues(p parse.Node, base yang.Type) map[string]int64 {
+	if base != nil {
+		return base.(*enumeration).values
+	}
+	values := make(map[string]int64)
+	next := int64(0)
+	for _, en := range p.ChildrenByType(parse.NodeEnum) {
+		val := next
+		if vn := en.ChildByType(parse.NodeValue); vn != nil {
+			if v, err := strconv.ParseInt(
+				vn.Argument().String(), 10, 64); err == nil {
+				val = v
+			}
+		}
+		values[en.ArgString()] = val
+		if val >= next {
+			next = val + 1
+		}
+	}
+	return values
+}
+
 func newEnumeration(
 	p parse.Node, base yang.Type, y yang.Enumeration, ext *extensions,
 ) (yang.Type, error) {
 
 	helpMap := parseEnumHelpMap(p, base)
-	return &enumeration{y, ext, helpMap}, nil
+	return &enumeration{y, ext, helpMap, parseEnumValues(p, base)}, nil
+}
+
+// EnumValue returns the integer value assigned to the enum name
+func (e *enumeration) EnumValue(name string) (int64, bool) {
+	val, ok := e.values[name]
+	return val, ok
+}
+
+// EnumName returns the enum assigned the integer value
+func (e *enumeration) EnumName(value int64) (string, bool) {
+	for name, val := range e.values {
+		if val == value {
+			return name, true
+		}
+	}
+	return "", false
 }
 
 type Identityref interface {
@@ -470,11 +514,34 @@ type instanceId struct {
 type Leafref interface {
 	yang.Leafref
 	hasExtensions
+	TargetPath() string
 }
 
 type leafref struct {
 	yang.Leafref
 	*extensions
+	path string
+}
+
+// Compile time check that the concrete type meets the interface
+var _ Leafref = (*leafref)(nil)
+
+func newLeafref(
+	p parse.Node, base yang.Type, y yang.Leafref, ext *extensions,
+) (yang.Type, error) {
+
+	var path string
+	if pn := p.ChildByType(parse.NodePath); pn != nil {
+		path = pn.Argument().String()
+	} else if b, ok := base.(*leafref); ok {
+		path = b.path
+	}
+	return &leafref{y, ext, path}, nil
+}
+
+// TargetPath is the leafref's path statement
+func (l *leafref) TargetPath() string {
+	return l.path
 }
 
 type Bits interface {
@@ -549,7 +616,7 @@ func (*CompilationExtensions) ExtendType(
 	case yang.InstanceId:
 		return &instanceId{y, ext}, nil
 	case yang.Leafref:
-		return &leafref{y, ext}, nil
+		return newLeafref(p, base, y, ext)
 	case yang.Bits:
 		return &bits{y, ext}, nil
 	default:
@@ -50,10 +50,15 @@ func configdEnv(sid string, path []string, action string, cact string) []string
 }
 
 func execCmd(sid, path, c string) (string, error) {
+	return execExtension(sid, "syntax", pathutil.Makepath(path), c)
+}
+
+// execExtension runs the script c of the given configd extension
+func execExtension(sid, action string, path []string, c string) (string, error) {
 
 	var env []string
 	env = append(env, os.Environ()...)
-	env = append(env, configdEnv(sid, pathutil.Makepath(path), "syntax", "")...)
+	env = append(env, configdEnv(sid, path, action, "")...)
 
 	var interpreter string
 	if sid == "" {
@@ -72,7 +77,7 @@ func execCmd(sid, path, c string) (string, error) {
 	}
 	if !cmd.ProcessState.Success() {
 		cerr := mgmterror.NewOperationFailedApplicationError()
-		cerr.Path = path
+		cerr.Path = pathutil.Pathstr(path)
 		cerr.Message = string(out)
 		return "", cerr
 	}
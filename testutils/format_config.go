@@ -34,7 +34,18 @@ func Rem(entry string) string {
 	return Prefix(entry, "-")
 }
 
-// Initially the +/- for changed lines get added right in front of the
+// Mov marks a moved ordered-by-user entry; only the entry itself and its
+// closing brace are marked, not its contents.
+func Mov(entry string) string {
+	lines := strings.SplitAfter(entry, "\n")
+	lines[0] = ">" + lines[0]
+	if last := len(lines) - 2; last > 0 && lines[last] == "}\n" {
+		lines[last] = ">" + lines[last]
+	}
+	return strings.Join(lines, "")
+}
+
+// Initially the +/-/> for changed lines get added right in front of the
 // element being changed.  This function pulls them to the front of the line
 // and inserts a leading space on unchanged lines.  Completely blank lines
 // (other than leading tabs) do NOT get a leading space.
@@ -43,11 +54,12 @@ func FormatAsDiff(entry string) (diffs string) {
 	for _, line := range lines {
 		trimmed := strings.Trim(line, "\t")
 		if len(trimmed) > 0 {
-			if trimmed[0] == '+' || trimmed[0] == '-' {
-				// Iteratively move + or - ahead of tabs
+			if trimmed[0] == '+' || trimmed[0] == '-' || trimmed[0] == '>' {
+				// Iteratively move +, - or > ahead of tabs
 				for line[0] == '\t' {
 					line = strings.Replace(line, "\t+", "+\t", 1)
 					line = strings.Replace(line, "\t-", "-\t", 1)
+					line = strings.Replace(line, "\t>", ">\t", 1)
 				}
 			} else {
 				line = " " + line
@@ -100,8 +112,8 @@ func ListEntry(name string, leaves ...string) (listEntryStr string) {
 
 func listOrLeafList(name string, entries []string) (retStr string) {
 	for _, entry := range entries {
-		// Deal with +/- prefix
-		if entry[0] == '+' || entry[0] == '-' {
+		// Deal with +/-/> prefix
+		if entry[0] == '+' || entry[0] == '-' || entry[0] == '>' {
 			retStr += fmt.Sprintf("%c%s %s", entry[0], name, entry[1:])
 		} else {
 			retStr += name + " " + entry
@@ -0,0 +1,750 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// RFC 7952 metadata annotations. Annotations are stored on the data nodes
+// under their module qualified name ("module:annotation") which is also
+// their RFC 7951 member name. In XML they are encoded as attributes in
+// the namespace of the defining module.
+
+func newInvalidAnnotationError(path []string, name string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = fmt.Sprintf(
+		"Annotation name must be module qualified: %s", name)
+	return err
+}
+
+func splitAnnotationName(name string) (module, local string, ok bool) {
+	i := strings.Index(name, ":")
+	if i <= 0 || i == len(name)-1 {
+		return "", "", false
+	}
+	return name[:i], name[i+1:], true
+}
+
+func (n *node) Annotations() map[string]string {
+	d := n.Data()
+	if d == nil {
+		return nil
+	}
+	return d.Annotations()
+}
+
+func (n *node) annotatedDescendant(auth Auther, path []string) (Node, error) {
+	if !authorize(auth, path, "update") {
+		return nil, autherr
+	}
+	target, err := n.descendant(path, make([]string, 0, len(path)))
+	if err != nil {
+		return nil, err
+	}
+	//Annotating a default would turn it into configuration
+	if target.def() {
+		return nil, yang.NewNodeNotExistsError(path)
+	}
+	return target, nil
+}
+
+// SetAnnotation sets the named annotation on the node at path,
+// copying it into the overlay if required.
+func (n *node) SetAnnotation(auth Auther, path []string, name, value string) error {
+	if _, _, ok := splitAnnotationName(name); !ok {
+		return newInvalidAnnotationError(path, name)
+	}
+	target, err := n.annotatedDescendant(auth, path)
+	if err != nil {
+		return err
+	}
+	j := n.journal()
+	j.Begin()
+	defer j.End()
+	target.copyUp().Data().SetAnnotation(name, value)
+	return nil
+}
+
+func (n *node) DeleteAnnotation(auth Auther, path []string, name string) error {
+	target, err := n.annotatedDescendant(auth, path)
+	if err != nil {
+		return err
+	}
+	if _, ok := target.Data().Annotation(name); !ok {
+		return nil
+	}
+	j := n.journal()
+	j.Begin()
+	defer j.End()
+	target.copyUp().Data().DeleteAnnotation(name)
+	return nil
+}
+
+// writeAnnotationObject writes the RFC 7952 metadata object
+func (b *JSONWriter) writeAnnotationObject(anns map[string]string) {
+	b.WriteByte('{')
+	for i, name := range data.SortedAnnotationNames(anns) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		// json.Marshal won't err on a string
+		buf, _ := json.Marshal(name)
+		b.Write(buf)
+		b.WriteByte(':')
+		if name == withDefaultsAnnotation {
+			//The with-defaults annotation is a boolean
+			b.WriteString(anns[name])
+			continue
+		}
+		buf, _ = json.Marshal(anns[name])
+		b.Write(buf)
+	}
+	b.WriteByte('}')
+}
+
+func (b *JSONWriter) writeAnnotations() bool {
+	return b.rfc7951 && b.annotations
+}
+
+func (b *JSONWriter) lastByte() byte {
+	if b.Len() == 0 {
+		if b.stream != nil {
+			return b.stream.last
+		}
+		return 0
+	}
+	return b.Bytes()[b.Len()-1]
+}
+
+// writeObjectAnnotations adds the "@" member to the object currently
+// being written for a container or list entry.
+func (b *JSONWriter) writeObjectAnnotations(n Node) {
+	anns := b.nodeAnnotations(n)
+	if len(anns) == 0 {
+		return
+	}
+	if c := b.lastByte(); c != '{' && c != ',' {
+		b.WriteByte(',')
+	}
+	b.WriteString("\"@\":")
+	b.writeAnnotationObject(anns)
+}
+
+// writeLeafAnnotations adds the "@name" sibling member for a leaf
+func (b *JSONWriter) writeLeafAnnotations(n *Leaf) {
+	anns := b.leafAnnotations(n, b.tagDefaults && n.isDefaultValue())
+	if len(anns) == 0 {
+		return
+	}
+	b.WriteString(",\"@")
+	b.WriteString(b.member)
+	b.WriteString("\":")
+	b.writeAnnotationObject(anns)
+}
+
+// writeLeafListAnnotations adds the "@name" sibling member for a
+// leaf-list, an array with an entry (or null) for each value.
+func (b *JSONWriter) writeLeafListAnnotations(n *LeafList) {
+	tagged := b.tagDefaults && n.isDefaultValue()
+	vals := n.SortedChildren()
+	found := false
+	for _, v := range vals {
+		if len(b.leafAnnotations(v, tagged)) > 0 {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+	b.WriteString(",\"@")
+	b.WriteString(b.member)
+	b.WriteString("\":[")
+	for i, v := range vals {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if anns := b.leafAnnotations(v, tagged); len(anns) > 0 {
+			b.writeAnnotationObject(anns)
+		} else {
+			b.WriteString("null")
+		}
+	}
+	b.WriteByte(']')
+}
+
+func rootNode(n Node) Node {
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		n = p
+	}
+	return n
+}
+
+func moduleNamespace(n Node, module string) (string, bool) {
+	ms, ok := rootNode(n).GetSchema().(schema.ModelSet)
+	if !ok {
+		return "", false
+	}
+	for _, mod := range ms.Modules() {
+		if mod.Identifier() == module {
+			return mod.(schema.Model).Namespace(), true
+		}
+	}
+	return "", false
+}
+
+func namespaceModule(sch schema.Node, namespace string) (string, bool) {
+	ms, ok := sch.(schema.ModelSet)
+	if !ok {
+		return "", false
+	}
+	for _, mod := range ms.Modules() {
+		if mod.(schema.Model).Namespace() == namespace {
+			return mod.Identifier(), true
+		}
+	}
+	return "", false
+}
+
+// annotationAttributes returns the XML attributes for the annotations
+// on n, in the namespace of the module defining each annotation. The
+// encoder declares the namespace prefixes. Annotations from modules not
+// in the model set can't be encoded and are skipped.
+func (enc *XMLWriter) annotationAttributes(n Node, attrs []xml.Attr) []xml.Attr {
+	anns := enc.nodeAnnotations(n)
+	for _, name := range data.SortedAnnotationNames(anns) {
+		module, local, _ := splitAnnotationName(name)
+		ns, ok := moduleNamespace(n, module)
+		if !ok && module == schema.OriginModule {
+			ns, ok = schema.OriginNamespace, true
+		}
+		if !ok {
+			continue
+		}
+		if module == schema.OriginModule {
+			//The origin is an identity, its prefix must be declared
+			attrs = append(attrs, xml.Attr{
+				Name:  xml.Name{Local: "xmlns:" + schema.OriginModule},
+				Value: ns})
+		}
+		attrs = append(attrs, xml.Attr{
+			Name:  xml.Name{Space: ns, Local: local},
+			Value: anns[name]})
+	}
+	return attrs
+}
+
+type annotationTarget struct {
+	path []string
+	anns map[string]string
+}
+
+func applyAnnotations(root Node, targets []annotationTarget) error {
+	for _, tgt := range targets {
+		for _, name := range data.SortedAnnotationNames(tgt.anns) {
+			err := root.SetAnnotation(nil, tgt.path, name, tgt.anns[name])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonLocalName(member string) string {
+	if i := strings.Index(member, ":"); i >= 0 {
+		return member[i+1:]
+	}
+	return member
+}
+
+func jsonScalarString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []interface{}:
+		// [null] is the encoding of an empty leaf
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+func jsonAnnotationObject(path []string, v interface{}) (map[string]string, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		err := mgmterror.NewInvalidValueApplicationError()
+		err.Path = pathutil.Pathstr(path)
+		err.Message = "Metadata object expected"
+		return nil, err
+	}
+	anns := make(map[string]string, len(obj))
+	for name, val := range obj {
+		if _, _, ok := splitAnnotationName(name); !ok {
+			return nil, newInvalidAnnotationError(path, name)
+		}
+		anns[name] = jsonScalarString(val)
+	}
+	return anns, nil
+}
+
+// listEntryName returns the name of the data node for a list entry from
+// the values of its keys, which must all be present. Entries of lists
+// with more than one key are named as in the RFC 7951 merge.
+func listEntryName(
+	sn schema.List,
+	keyValue func(key string) (string, bool),
+) (string, bool) {
+	keys := sn.Keys()
+	vals := make([]string, 0, len(keys))
+	for _, key := range keys {
+		val, ok := keyValue(key)
+		if !ok || val == "" {
+			return "", false
+		}
+		vals = append(vals, val)
+	}
+	return strings.Join(vals, "·"), len(vals) > 0
+}
+
+// jsonAnnotation is an RFC 7951 metadata object, not yet decoded, with
+// the path of the node it is attached to.
+type jsonAnnotation struct {
+	path []string
+	val  interface{}
+}
+
+func prefixJSONAnnotations(prefix []string, in []jsonAnnotation) []jsonAnnotation {
+	for i := range in {
+		in[i].path = append(pathutil.Copypath(prefix), in[i].path...)
+	}
+	return in
+}
+
+// jsonStripper copies an RFC 7951 document a token at a time, leaving
+// out the metadata members, so that the order of all other members is
+// unchanged. The paths of the metadata found are relative to the
+// object being copied as the names of list entries are only known once
+// all of their keys have been read.
+type jsonStripper struct {
+	dec *json.Decoder
+	out bytes.Buffer
+}
+
+func (s *jsonStripper) token() (json.Token, error) {
+	tok, err := s.dec.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return tok, err
+}
+
+func (s *jsonStripper) writeToken(tok json.Token) {
+	switch t := tok.(type) {
+	case json.Delim:
+		s.out.WriteString(t.String())
+	case json.Number:
+		s.out.WriteString(t.String())
+	case nil:
+		s.out.WriteString("null")
+	default:
+		// json.Marshal won't err on a string or bool
+		buf, _ := json.Marshal(t)
+		s.out.Write(buf)
+	}
+}
+
+// copyValue copies the value starting with tok without interpreting it
+func (s *jsonStripper) copyValue(tok json.Token) error {
+	s.writeToken(tok)
+	isObject := tok == json.Delim('{')
+	if !isObject && tok != json.Delim('[') {
+		return nil
+	}
+	for i := 0; s.dec.More(); i++ {
+		if i > 0 {
+			s.out.WriteByte(',')
+		}
+		tok, err := s.token()
+		if err != nil {
+			return err
+		}
+		if isObject {
+			s.writeToken(tok)
+			s.out.WriteByte(':')
+			if tok, err = s.token(); err != nil {
+				return err
+			}
+		}
+		if err := s.copyValue(tok); err != nil {
+			return err
+		}
+	}
+	tok, err := s.token()
+	if err != nil {
+		return err
+	}
+	s.writeToken(tok)
+	return nil
+}
+
+// copyScalars copies the value starting with tok, returning the string
+// form of the scalars in it; for a leaf-list the values, for a leaf its
+// value.
+func (s *jsonStripper) copyScalars(tok json.Token) ([]string, error) {
+	if tok != json.Delim('[') {
+		s.writeToken(tok)
+		return []string{jsonScalarString(tok)}, nil
+	}
+	s.writeToken(tok)
+	var vals []string
+	for i := 0; s.dec.More(); i++ {
+		if i > 0 {
+			s.out.WriteByte(',')
+		}
+		tok, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := tok.(json.Delim); ok {
+			//[null] is the encoding of an empty leaf
+			vals = append(vals, "")
+			if err := s.copyValue(tok); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		s.writeToken(tok)
+		vals = append(vals, jsonScalarString(tok))
+	}
+	tok, err := s.token()
+	if err != nil {
+		return nil, err
+	}
+	s.writeToken(tok)
+	return vals, nil
+}
+
+// object copies an object, for a container, list entry or the top level,
+// whose opening brace has already been read. The values of its leaves
+// are returned so that list entries can be named from their keys.
+func (s *jsonStripper) object(
+	sn schema.Node,
+) ([]jsonAnnotation, map[string]string, error) {
+	var out []jsonAnnotation
+	leaves := make(map[string]string)
+	leafLists := make(map[string][]string)
+	leafListAnns := make(map[string]interface{})
+
+	s.writeToken(json.Delim('{'))
+	written := 0
+	for s.dec.More() {
+		tok, err := s.token()
+		if err != nil {
+			return nil, nil, err
+		}
+		member, _ := tok.(string)
+		name := jsonLocalName(member)
+		if strings.HasPrefix(member, "@") {
+			var val interface{}
+			if err := s.dec.Decode(&val); err != nil {
+				return nil, nil, err
+			}
+			if member == "@" {
+				out = append(out, jsonAnnotation{path: []string{}, val: val})
+				continue
+			}
+			name = jsonLocalName(member[1:])
+			switch sn.SchemaChild(name).(type) {
+			case schema.Leaf:
+				out = append(out, jsonAnnotation{
+					path: []string{name}, val: val})
+			case schema.LeafList:
+				leafListAnns[name] = val
+			}
+			continue
+		}
+
+		if written > 0 {
+			s.out.WriteByte(',')
+		}
+		written++
+		s.writeToken(member)
+		s.out.WriteByte(':')
+		if tok, err = s.token(); err != nil {
+			return nil, nil, err
+		}
+		switch v := sn.SchemaChild(name).(type) {
+		case schema.Container:
+			if tok != json.Delim('{') {
+				break
+			}
+			anns, _, err := s.object(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, prefixJSONAnnotations([]string{name}, anns)...)
+			continue
+		case schema.List:
+			if tok != json.Delim('[') {
+				break
+			}
+			anns, err := s.list(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			out = append(out, prefixJSONAnnotations([]string{name}, anns)...)
+			continue
+		case schema.Leaf:
+			vals, err := s.copyScalars(tok)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(vals) == 1 {
+				leaves[name] = vals[0]
+			}
+			continue
+		case schema.LeafList:
+			vals, err := s.copyScalars(tok)
+			if err != nil {
+				return nil, nil, err
+			}
+			leafLists[name] = vals
+			continue
+		}
+		if err := s.copyValue(tok); err != nil {
+			return nil, nil, err
+		}
+	}
+	tok, err := s.token()
+	if err != nil {
+		return nil, nil, err
+	}
+	s.writeToken(tok)
+
+	//The metadata for a leaf-list may precede the values
+	names := make([]string, 0, len(leafListAnns))
+	for name := range leafListAnns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		annlist, _ := leafListAnns[name].([]interface{})
+		vals := leafLists[name]
+		for i, ann := range annlist {
+			if ann == nil || i >= len(vals) {
+				continue
+			}
+			out = append(out, jsonAnnotation{
+				path: []string{name, vals[i]}, val: ann})
+		}
+	}
+	return out, leaves, nil
+}
+
+// list copies the array of entries of a list, whose opening bracket has
+// already been read.
+func (s *jsonStripper) list(sn schema.List) ([]jsonAnnotation, error) {
+	var out []jsonAnnotation
+	entry := sn.SchemaChild(sn.Keys()[0])
+	s.writeToken(json.Delim('['))
+	for i := 0; s.dec.More(); i++ {
+		if i > 0 {
+			s.out.WriteByte(',')
+		}
+		tok, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok != json.Delim('{') || entry == nil {
+			if err := s.copyValue(tok); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		anns, leaves, err := s.object(entry)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := listEntryName(sn, func(key string) (string, bool) {
+			val, ok := leaves[key]
+			return val, ok
+		})
+		if !ok {
+			//Leave it to the decoder proper to report the missing key
+			continue
+		}
+		out = append(out, prefixJSONAnnotations([]string{name}, anns)...)
+	}
+	tok, err := s.token()
+	if err != nil {
+		return nil, err
+	}
+	s.writeToken(tok)
+	return out, nil
+}
+
+// stripRFC7951Annotations splits the metadata out of an RFC 7951
+// document, returning the document without it so it can be passed on to
+// the schema aware decoder.
+func stripRFC7951Annotations(
+	sn schema.Node,
+	jsonInput []byte,
+) ([]byte, []annotationTarget, error) {
+	if !bytes.Contains(jsonInput, []byte("\"@")) {
+		return jsonInput, nil, nil
+	}
+	s := &jsonStripper{dec: json.NewDecoder(bytes.NewReader(jsonInput))}
+	s.dec.UseNumber()
+	tok, err := s.token()
+	if err != nil || tok != json.Delim('{') {
+		//Leave it to the decoder proper to report the error
+		return jsonInput, nil, nil
+	}
+	found, _, err := s.object(sn)
+	if err != nil {
+		return jsonInput, nil, nil
+	}
+	out := make([]annotationTarget, 0, len(found))
+	for _, ann := range found {
+		anns, err := jsonAnnotationObject(ann.path, ann.val)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, annotationTarget{path: ann.path, anns: anns})
+	}
+	return s.out.Bytes(), out, nil
+}
+
+type xmlElement struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*xmlElement
+}
+
+func parseXMLElements(input []byte) (*xmlElement, error) {
+	dec := xml.NewDecoder(bytes.NewReader(input))
+	root := &xmlElement{}
+	stack := []*xmlElement{root}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if len(stack) == 1 {
+				return root, nil
+			}
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			elem := &xmlElement{name: t.Name, attrs: t.Copy().Attr}
+			top.children = append(top.children, elem)
+			stack = append(stack, elem)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			top.text += string(t)
+		}
+	}
+}
+
+// xmlAnnotations returns the annotations encoded as attributes of the
+// element. Attributes whose namespace isn't that of a module in the
+// model set (eg namespace declarations) are ignored.
+func xmlAnnotations(ms schema.Node, elem *xmlElement) map[string]string {
+	var anns map[string]string
+	for _, attr := range elem.attrs {
+		if attr.Name.Space == "" || attr.Name.Space == "xmlns" {
+			continue
+		}
+		module, ok := namespaceModule(ms, attr.Name.Space)
+		if !ok {
+			continue
+		}
+		if anns == nil {
+			anns = make(map[string]string)
+		}
+		anns[module+":"+attr.Name.Local] = attr.Value
+	}
+	return anns
+}
+
+func extractXMLAnnotations(
+	ms, sn schema.Node,
+	elem *xmlElement,
+	path []string,
+) []annotationTarget {
+	var out []annotationTarget
+	for _, ch := range elem.children {
+		name := ch.name.Local
+		csn := sn.SchemaChild(name)
+		cpath := pathutil.CopyAppend(path, name)
+		switch v := csn.(type) {
+		case schema.Container:
+			if anns := xmlAnnotations(ms, ch); anns != nil {
+				out = append(out, annotationTarget{path: cpath, anns: anns})
+			}
+			out = append(out, extractXMLAnnotations(ms, v, ch, cpath)...)
+		case schema.List:
+			key, ok := listEntryName(v, func(keyname string) (string, bool) {
+				for _, k := range ch.children {
+					if k.name.Local == keyname {
+						return strings.TrimSpace(k.text), true
+					}
+				}
+				return "", false
+			})
+			esn := v.SchemaChild(key)
+			if !ok || esn == nil {
+				continue
+			}
+			epath := pathutil.CopyAppend(cpath, key)
+			if anns := xmlAnnotations(ms, ch); anns != nil {
+				out = append(out, annotationTarget{path: epath, anns: anns})
+			}
+			out = append(out, extractXMLAnnotations(ms, esn, ch, epath)...)
+		case schema.Leaf:
+			if anns := xmlAnnotations(ms, ch); anns != nil {
+				out = append(out, annotationTarget{path: cpath, anns: anns})
+			}
+		case schema.LeafList:
+			if anns := xmlAnnotations(ms, ch); anns != nil {
+				out = append(out, annotationTarget{
+					path: pathutil.CopyAppend(cpath, strings.TrimSpace(ch.text)),
+					anns: anns})
+			}
+		}
+	}
+	return out
+}
+
+// xmlInputAnnotations finds the annotations in an XML document whose
+// top level element encloses the configuration.
+func xmlInputAnnotations(sn schema.Node, input []byte) ([]annotationTarget, error) {
+	doc, err := parseXMLElements(input)
+	if err != nil {
+		return nil, err
+	}
+	var out []annotationTarget
+	for _, top := range doc.children {
+		out = append(out, extractXMLAnnotations(sn, sn, top, []string{})...)
+	}
+	return out, nil
+}
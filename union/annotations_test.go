@@ -0,0 +1,194 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const annotationSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf-list tags {
+			type string;
+		}
+		list entry {
+			key id;
+			leaf id {
+				type string;
+			}
+			leaf val {
+				type string;
+			}
+		}
+	}`
+
+func newAnnotationTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, annotationSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	for _, path := range [][]string{
+		{"top", "name", "foo"},
+		{"top", "tags", "a"},
+		{"top", "tags", "b"},
+	} {
+		if err := root.Set(nil, path); err != nil {
+			t.Fatalf("Unexpected set error: %s", err)
+		}
+	}
+	return root
+}
+
+func checkAnnotation(t *testing.T, root Node, path []string, name, expected string) {
+	t.Helper()
+	n, err := root.Descendant(nil, path)
+	if err != nil {
+		t.Fatalf("Unexpected descendant error: %s", err)
+	}
+	actual, ok := n.Annotations()[name]
+	if !ok || actual != expected {
+		t.Errorf("Annotation %s on %v: expected %q, got %q (found %t)",
+			name, path, expected, actual, ok)
+	}
+}
+
+func TestSetAnnotationRequiresModulePrefix(t *testing.T) {
+	root := newAnnotationTestTree(t)
+	err := root.SetAnnotation(nil, []string{"top", "name"}, "note", "x")
+	if err == nil {
+		t.Fatalf("Unqualified annotation name was accepted")
+	}
+}
+
+func TestSetAnnotationMissingNode(t *testing.T) {
+	root := newAnnotationTestTree(t)
+	err := root.SetAnnotation(nil, []string{"top", "tags", "c"},
+		"test-union:note", "x")
+	if err == nil {
+		t.Fatalf("Annotation set on non-existent node")
+	}
+}
+
+func TestDeleteAnnotation(t *testing.T) {
+	root := newAnnotationTestTree(t)
+	path := []string{"top", "name"}
+	if err := root.SetAnnotation(nil, path, "test-union:note", "x"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	checkAnnotation(t, root, path, "test-union:note", "x")
+	if err := root.DeleteAnnotation(nil, path, "test-union:note"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	n, _ := root.Descendant(nil, path)
+	if anns := n.Annotations(); anns != nil {
+		t.Errorf("Annotations remain after delete: %v", anns)
+	}
+}
+
+func TestRFC7951AnnotationEncoding(t *testing.T) {
+	root := newAnnotationTestTree(t)
+	root.SetAnnotation(nil, []string{"top"}, "test-union:note", "container")
+	root.SetAnnotation(nil, []string{"top", "name"}, "test-union:note", "leaf")
+	root.SetAnnotation(nil, []string{"top", "tags", "b"},
+		"test-union:note", "value")
+
+	expected := `{"test-union:top":{` +
+		`"name":"foo","@name":{"test-union:note":"leaf"},` +
+		`"tags":["a","b"],"@tags":[null,{"test-union:note":"value"}],` +
+		`"@":{"test-union:note":"container"}}}`
+	actual := string(root.ToRFC7951(IncludeAnnotations))
+	if actual != expected {
+		t.Errorf("Unexpected RFC 7951 encoding\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+
+	without := `{"test-union:top":{"name":"foo","tags":["a","b"]}}`
+	if actual := string(root.ToRFC7951()); actual != without {
+		t.Errorf("Annotations encoded without IncludeAnnotations\n"+
+			"   expect=%s\n   actual=%s", without, actual)
+	}
+}
+
+func TestRFC7951AnnotationRoundTrip(t *testing.T) {
+	input := `{"test-union:top":{` +
+		`"@":{"test-union:note":"container"},` +
+		`"name":"foo","@name":{"test-union:note":"leaf"},` +
+		`"tags":["a","b"],"@tags":[null,{"test-union:note":"value"}]}}`
+
+	root, err := UnmarshalRFC7951(newTestSchema(t, annotationSchema),
+		[]byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected unmarshal error: %s", err)
+	}
+	checkAnnotation(t, root, []string{"top"}, "test-union:note", "container")
+	checkAnnotation(t, root, []string{"top", "name"}, "test-union:note", "leaf")
+	checkAnnotation(t, root, []string{"top", "tags", "b"},
+		"test-union:note", "value")
+}
+
+func TestXMLAnnotationRoundTrip(t *testing.T) {
+	root := newAnnotationTestTree(t)
+	root.SetAnnotation(nil, []string{"top", "name"}, "test-union:note", "leaf")
+
+	expected := `<data>` +
+		`<top xmlns="urn:vyatta.com:test:union">` +
+		`<name xmlns="urn:vyatta.com:test:union" ` +
+		`xmlns:_="urn:vyatta.com:test:union" ` +
+		`_:note="leaf">foo</name>` +
+		`<tags xmlns="urn:vyatta.com:test:union">a</tags>` +
+		`<tags xmlns="urn:vyatta.com:test:union">b</tags>` +
+		`</top></data>`
+	actual := string(root.ToXML("data", IncludeAnnotations))
+	if actual != expected {
+		t.Fatalf("Unexpected XML encoding\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+
+	decoded, err := UnmarshalXML(newTestSchema(t, annotationSchema),
+		[]byte(actual))
+	if err != nil {
+		t.Fatalf("Unexpected unmarshal error: %s", err)
+	}
+	checkAnnotation(t, decoded, []string{"top", "name"},
+		"test-union:note", "leaf")
+}
+
+func TestStripRFC7951AnnotationsKeepsOrder(t *testing.T) {
+	input := `{"test-union:top":{` +
+		`"tags":["a","b"],"@tags":[{"test-union:note":"value"},null],` +
+		`"entry":[{"@":{"test-union:note":"entry"},"val":"x","id":"one",` +
+		`"@val":{"test-union:note":"leaf"}}],` +
+		`"@name":{"test-union:note":"leaf"},"name":"foo"}}`
+	expected := `{"test-union:top":{"tags":["a","b"],` +
+		`"entry":[{"val":"x","id":"one"}],"name":"foo"}}`
+
+	sch := newTestSchema(t, annotationSchema)
+	out, anns, err := stripRFC7951Annotations(sch, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(out) != expected {
+		t.Errorf("Unexpected stripped document\n   expect=%s\n   actual=%s",
+			expected, out)
+	}
+	if len(anns) != 4 {
+		t.Errorf("Expected 4 annotations, got %v", anns)
+	}
+
+	root, err := UnmarshalRFC7951(sch, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected unmarshal error: %s", err)
+	}
+	checkAnnotation(t, root, []string{"top", "name"}, "test-union:note", "leaf")
+	checkAnnotation(t, root, []string{"top", "tags", "a"},
+		"test-union:note", "value")
+	checkAnnotation(t, root, []string{"top", "entry", "one"},
+		"test-union:note", "entry")
+	checkAnnotation(t, root, []string{"top", "entry", "one", "val"},
+		"test-union:note", "leaf")
+}
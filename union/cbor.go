@@ -0,0 +1,349 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/danos/config/schema"
+	yang "github.com/danos/yang/schema"
+)
+
+// YANG-CBOR encoding (RFC 9254). Members are keyed by name, qualified as
+// in RFC 7951, or, given a SIDMap, by the difference between their SID
+// and that of their parent. A node without a SID is keyed by name, and
+// its children by absolute SID (tag 47) where they have one. As the
+// number of children to be serialized isn't known up front, containers,
+// lists and list entries are encoded with indefinite lengths.
+//
+// Bits and instance-identifier values are encoded as text strings.
+
+const (
+	cborUint   = 0
+	cborNegint = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+
+	cborIndefinite = 31
+	cborFalse      = 0xf4
+	cborTrue       = 0xf5
+	cborNull       = 0xf6
+	cborBreak      = 0xff
+
+	cborTagDecimal     = 4
+	cborTagEnumeration = 44
+	cborTagIdentityref = 45
+	cborTagSID         = 47
+)
+
+// SIDs keys the CBOR encoding by the SIDs in m rather than by name.
+func SIDs(m *SIDMap) UnionOption {
+	return func(opts *unionOptions) {
+		opts.sids = m
+	}
+}
+
+func writeCBORHead(b *bytes.Buffer, major byte, val uint64) {
+	switch {
+	case val < 24:
+		b.WriteByte(major<<5 | byte(val))
+	case val <= math.MaxUint8:
+		b.WriteByte(major<<5 | 24)
+		b.WriteByte(byte(val))
+	case val <= math.MaxUint16:
+		b.WriteByte(major<<5 | 25)
+		b.WriteByte(byte(val >> 8))
+		b.WriteByte(byte(val))
+	case val <= math.MaxUint32:
+		b.WriteByte(major<<5 | 26)
+		for shift := 24; shift >= 0; shift -= 8 {
+			b.WriteByte(byte(val >> uint(shift)))
+		}
+	default:
+		b.WriteByte(major<<5 | 27)
+		for shift := 56; shift >= 0; shift -= 8 {
+			b.WriteByte(byte(val >> uint(shift)))
+		}
+	}
+}
+
+func writeCBORInt(b *bytes.Buffer, val int64) {
+	if val < 0 {
+		writeCBORHead(b, cborNegint, uint64(-(val + 1)))
+		return
+	}
+	writeCBORHead(b, cborUint, uint64(val))
+}
+
+func writeCBORText(b *bytes.Buffer, s string) {
+	writeCBORHead(b, cborText, uint64(len(s)))
+	b.WriteString(s)
+}
+
+// cborFrame is a node whose children are being written: its SID
+// identifier path and module, and its SID if it has one.
+type cborFrame struct {
+	path   string
+	module string
+	sid    uint64
+	hasSID bool
+}
+
+type CBORWriter struct {
+	bytes.Buffer
+	sids   *SIDMap
+	frames []cborFrame
+}
+
+func (b *CBORWriter) parent() cborFrame {
+	return b.frames[len(b.frames)-1]
+}
+
+// writeMember writes the key of the child name of module, and starts a
+// frame for its own children.
+func (b *CBORWriter) writeMember(module, name string) {
+	parent := b.parent()
+	frame := cborFrame{
+		path:   sidStep(parent.path, parent.module, module, name),
+		module: module,
+	}
+	frame.sid, frame.hasSID = b.sids.dataSID(frame.path)
+	switch {
+	case frame.hasSID && parent.hasSID:
+		writeCBORInt(&b.Buffer, int64(frame.sid-parent.sid))
+	case frame.hasSID:
+		writeCBORHead(&b.Buffer, cborTag, cborTagSID)
+		writeCBORHead(&b.Buffer, cborUint, frame.sid)
+	case module != parent.module:
+		writeCBORText(&b.Buffer, module+":"+name)
+	default:
+		writeCBORText(&b.Buffer, name)
+	}
+	b.frames = append(b.frames, frame)
+}
+
+func (b *CBORWriter) endMember() {
+	b.frames = b.frames[:len(b.frames)-1]
+}
+
+func (b *CBORWriter) writeValue(n Node, val string) {
+	b.writeTypedValue(n, n.GetSchema().Type(), val, false)
+}
+
+func (b *CBORWriter) writeTypedValue(n Node, typ yang.Type, val string, inUnion bool) {
+	switch t := typ.(type) {
+	case schema.Union:
+		if mt := t.MatchType(nil, []string{}, val); mt != nil {
+			b.writeTypedValue(n, mt, val, true)
+			return
+		}
+	case schema.Integer:
+		if v, err := strconv.ParseInt(val, 10, 64); err == nil {
+			writeCBORInt(&b.Buffer, v)
+			return
+		}
+	case schema.Uinteger:
+		if v, err := strconv.ParseUint(val, 10, 64); err == nil {
+			writeCBORHead(&b.Buffer, cborUint, v)
+			return
+		}
+	case schema.Boolean:
+		if val == "true" {
+			b.WriteByte(cborTrue)
+		} else {
+			b.WriteByte(cborFalse)
+		}
+		return
+	case schema.Empty:
+		b.WriteByte(cborNull)
+		return
+	case schema.Decimal64:
+		if exp, mant, ok := decimalFraction(val); ok {
+			writeCBORHead(&b.Buffer, cborTag, cborTagDecimal)
+			writeCBORHead(&b.Buffer, cborArray, 2)
+			writeCBORInt(&b.Buffer, exp)
+			writeCBORInt(&b.Buffer, mant)
+			return
+		}
+	case schema.Binary:
+		if v, err := base64.StdEncoding.DecodeString(val); err == nil {
+			writeCBORHead(&b.Buffer, cborBytes, uint64(len(v)))
+			b.Write(v)
+			return
+		}
+	case schema.Enumeration:
+		if inUnion {
+			writeCBORHead(&b.Buffer, cborTag, cborTagEnumeration)
+		} else if v, ok := t.EnumValue(val); ok {
+			writeCBORInt(&b.Buffer, v)
+			return
+		}
+	case schema.Identityref:
+		for _, idn := range t.Identities() {
+			if idn.Val != val {
+				continue
+			}
+			name := idn.Module + ":" + idn.Val
+			if sid, ok := b.sids.identitySID(name); ok {
+				if inUnion {
+					writeCBORHead(&b.Buffer, cborTag, cborTagIdentityref)
+				}
+				writeCBORHead(&b.Buffer, cborUint, sid)
+				return
+			}
+			writeCBORText(&b.Buffer, name)
+			return
+		}
+	case schema.Leafref:
+		root := rootNode(n).GetSchema()
+		if target := leafrefTarget(root, valueLeafPath(n), t); target != nil {
+			b.writeTypedValue(n, target.Type(), val, inUnion)
+			return
+		}
+	}
+	writeCBORText(&b.Buffer, val)
+}
+
+// valueLeafPath returns the data path of the leaf holding the value n, a
+// leaf value or the key of a list entry.
+func valueLeafPath(n Node) []string {
+	if e, ok := n.(*ListEntry); ok {
+		return append(nodePath(e), e.Schema.Keys()[0])
+	}
+	return nodePath(n.Parent())
+}
+
+// decimalFraction splits a decimal64 value into the exponent and mantissa
+// of a CBOR decimal fraction.
+func decimalFraction(val string) (int64, int64, bool) {
+	digits := val
+	var exp int64
+	if i := strings.Index(val, "."); i >= 0 {
+		digits = val[:i] + val[i+1:]
+		exp = -int64(len(val) - i - 1)
+	}
+	mant, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return exp, mant, true
+}
+
+func (b *CBORWriter) BeginContainer(n *Container, empty bool, level int) {
+	b.writeMember(n.Module(), n.Name())
+	if empty {
+		writeCBORHead(&b.Buffer, cborMap, 0)
+		return
+	}
+	b.WriteByte(cborMap<<5 | cborIndefinite)
+}
+
+func (b *CBORWriter) EndContainer(n *Container, empty bool, level int) {
+	if !empty {
+		b.WriteByte(cborBreak)
+	}
+	b.endMember()
+}
+
+func (b *CBORWriter) BeginList(n *List, empty bool, level int) {
+	b.writeMember(n.Module(), n.Name())
+	b.WriteByte(cborArray<<5 | cborIndefinite)
+}
+
+func (b *CBORWriter) EndList(n *List, empty bool, level int) {
+	b.WriteByte(cborBreak)
+	b.endMember()
+}
+
+func (b *CBORWriter) BeginListEntry(n *ListEntry, empty bool, level int, hideSecrets bool) {
+	b.WriteByte(cborMap<<5 | cborIndefinite)
+	b.writeMember(b.parent().module, n.Schema.Keys()[0])
+	if redactListEntry(n, hideSecrets) {
+		writeCBORText(&b.Buffer, "********")
+	} else {
+		b.writeValue(n, n.Name())
+	}
+	b.endMember()
+}
+
+func (b *CBORWriter) EndListEntry(n *ListEntry, empty bool, level int) {
+	b.WriteByte(cborBreak)
+}
+
+func (b *CBORWriter) BeginLeaf(n *Leaf, empty bool, level int, hideSecrets bool) {
+	b.writeMember(n.Module(), n.Name())
+}
+
+func (b *CBORWriter) WriteLeafValue(n *Leaf, empty bool, level int, hideSecrets bool) {
+	vals := n.SortedChildren()
+	switch {
+	case len(vals) == 0:
+		b.WriteByte(cborNull)
+	case hideSecrets && n.GetSchema().ConfigdExt().Secret:
+		writeCBORText(&b.Buffer, "********")
+	default:
+		b.writeValue(vals[0], vals[0].Name())
+	}
+}
+
+func (b *CBORWriter) EndLeaf(n *Leaf, empty bool, level int) {
+	b.endMember()
+}
+
+func (b *CBORWriter) BeginLeafList(n *LeafList, empty bool, level int, hideSecrets bool) {
+	b.writeMember(n.Module(), n.Name())
+}
+
+func (b *CBORWriter) WriteLeafListValues(n *LeafList, empty bool, level int, hideSecrets bool) {
+	vals := n.SortedChildren()
+	writeCBORHead(&b.Buffer, cborArray, uint64(len(vals)))
+	hide := hideSecrets && n.GetSchema().ConfigdExt().Secret
+	for _, v := range vals {
+		if hide {
+			writeCBORText(&b.Buffer, "********")
+		} else {
+			b.writeValue(v, v.Name())
+		}
+	}
+}
+
+func (b *CBORWriter) EndLeafList(n *LeafList, empty bool, level int) {
+	b.endMember()
+}
+
+func (b *CBORWriter) PrintSep() {}
+
+func (n *node) ToCBOR(options ...UnionOption) []byte {
+	var opts unionOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	b := &CBORWriter{sids: opts.sids}
+	frame := cborFrame{hasSID: true}
+	if n.Parent() != nil {
+		frame.path, frame.module = sidPathOf(n.specialized)
+		frame.sid, frame.hasSID = b.sids.dataSID(frame.path)
+	}
+	b.frames = []cborFrame{frame}
+
+	switch n.specialized.(type) {
+	case *ListEntry:
+		//As with JSON, list entries are already enclosed in a map
+		n.Serialize(b, nil, options...)
+	default:
+		b.WriteByte(cborMap<<5 | cborIndefinite)
+		n.Serialize(b, nil, options...)
+		b.WriteByte(cborBreak)
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,573 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	"github.com/danos/yang/data/datanode"
+	yang "github.com/danos/yang/schema"
+)
+
+// Decoding of YANG-CBOR. The document is read into generic values, which
+// are then converted to a data tree by convertToDataNode. Members may be
+// keyed by name, by SID delta or by absolute SID.
+
+type cborPair struct {
+	key, val interface{}
+}
+
+type cborMapValue []cborPair
+
+type cborTagValue struct {
+	num uint64
+	val interface{}
+}
+
+// cborBreakValue ends an indefinite length item
+type cborBreakValue struct{}
+
+var errCBORTruncated = errors.New("Unexpected end of CBOR data")
+
+// cborMaxDepth limits the nesting of arrays, maps and tags, so that a
+// hostile document can't exhaust the stack.
+const cborMaxDepth = 256
+
+type cborReader struct {
+	buf   []byte
+	pos   int
+	depth int
+}
+
+func (r *cborReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.buf)-r.pos) {
+		return nil, errCBORTruncated
+	}
+	out := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return out, nil
+}
+
+// head reads the initial byte of an item and its argument.
+func (r *cborReader) head() (major, info byte, arg uint64, err error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		bs, err := r.next(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		for _, b := range bs {
+			arg = arg<<8 | uint64(b)
+		}
+		return major, info, arg, nil
+	case info == cborIndefinite && major != cborUint &&
+		major != cborNegint && major != cborTag:
+		return major, info, 0, nil
+	}
+	return 0, 0, 0, fmt.Errorf("Invalid CBOR item 0x%02x", b[0])
+}
+
+func (r *cborReader) read() (interface{}, error) {
+	if r.depth >= cborMaxDepth {
+		return nil, fmt.Errorf("CBOR data nested too deeply")
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+
+	major, info, arg, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == cborIndefinite
+	switch major {
+	case cborUint:
+		return arg, nil
+	case cborNegint:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("CBOR integer out of range")
+		}
+		return -1 - int64(arg), nil
+	case cborBytes, cborText:
+		var out []byte
+		if !indefinite {
+			if out, err = r.next(arg); err != nil {
+				return nil, err
+			}
+		}
+		//Chunks of an indefinite length string are definite length
+		//strings of the same major type
+		for indefinite {
+			cmajor, cinfo, carg, err := r.head()
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case cmajor == cborSimple && cinfo == cborIndefinite:
+				indefinite = false
+			case cmajor != major || cinfo == cborIndefinite:
+				return nil, fmt.Errorf("Invalid CBOR string chunk")
+			default:
+				chunk, err := r.next(carg)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, chunk...)
+			}
+		}
+		if major == cborText {
+			return string(out), nil
+		}
+		return append([]byte(nil), out...), nil
+	case cborArray:
+		var out []interface{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			item, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := item.(cborBreakValue); ok {
+				if !indefinite {
+					return nil, fmt.Errorf("Unexpected CBOR break")
+				}
+				break
+			}
+			out = append(out, item)
+		}
+		return out, nil
+	case cborMap:
+		var out cborMapValue
+		for i := uint64(0); indefinite || i < arg; i++ {
+			key, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := key.(cborBreakValue); ok {
+				if !indefinite {
+					return nil, fmt.Errorf("Unexpected CBOR break")
+				}
+				break
+			}
+			val, err := r.read()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, cborPair{key: key, val: val})
+		}
+		return out, nil
+	case cborTag:
+		val, err := r.read()
+		if err != nil {
+			return nil, err
+		}
+		return cborTagValue{num: arg, val: val}, nil
+	}
+
+	switch {
+	case info == 20:
+		return false, nil
+	case info == 21:
+		return true, nil
+	case info == 22, info == 23:
+		return nil, nil
+	case info == 25:
+		return halfFloat(uint16(arg)), nil
+	case info == 26:
+		return float64(math.Float32frombits(uint32(arg))), nil
+	case info == 27:
+		return math.Float64frombits(arg), nil
+	case indefinite:
+		return cborBreakValue{}, nil
+	}
+	return nil, fmt.Errorf("Unsupported CBOR simple value %d", arg)
+}
+
+func halfFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var val float64
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 31:
+		val = math.Inf(1)
+		if mant != 0 {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -val
+	}
+	return val
+}
+
+// cborDecoder holds what is common to the whole document.
+type cborDecoder struct {
+	sids *SIDMap
+	root schema.Node
+}
+
+// cborUnserialized is a node of a decoded CBOR document. It carries the
+// node's SID identifier path, needed to resolve its children's SIDs.
+type cborUnserialized struct {
+	dec    *cborDecoder
+	member string
+	val    interface{}
+	sn     yang.Node
+	path   []string
+	frame  cborFrame
+}
+
+func newCBORTypeError(path []string, msg string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = msg
+	return err
+}
+
+func (u *cborUnserialized) name() string {
+	return u.member
+}
+
+// decimalString formats a CBOR decimal fraction as a decimal64 value.
+func decimalString(exp, mant int64) string {
+	digits := strconv.FormatInt(mant, 10)
+	if exp >= 0 {
+		return digits + strings.Repeat("0", int(exp))
+	}
+	sign := ""
+	if mant < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	frac := int(-exp)
+	if len(digits) <= frac {
+		digits = strings.Repeat("0", frac-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-frac] + "." + digits[len(digits)-frac:]
+}
+
+func cborInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case uint64:
+		if n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// scalar converts a CBOR value to the string value of the leaf or leaf
+// list u, of type typ.
+func (u *cborUnserialized) scalar(typ yang.Type, v interface{}) (string, error) {
+	switch t := typ.(type) {
+	case schema.Enumeration:
+		if n, ok := cborInt(v); ok {
+			if name, ok := t.EnumName(n); ok {
+				return name, nil
+			}
+			return "", newCBORTypeError(u.path,
+				fmt.Sprintf("Unknown enumeration value %d", n))
+		}
+	case schema.Identityref:
+		if sid, ok := v.(uint64); ok {
+			name, ok := u.dec.sids.identityName(sid)
+			if !ok {
+				return "", newCBORTypeError(u.path,
+					fmt.Sprintf("Unknown identity SID %d", sid))
+			}
+			v = name
+		}
+		if s, ok := v.(string); ok {
+			return stripIdentityPrefix(t, s), nil
+		}
+	case schema.Leafref:
+		target := leafrefTarget(u.dec.root, u.path, t)
+		if target != nil {
+			return u.scalar(target.Type(), v)
+		}
+	case schema.Union:
+		if s, ok := v.(string); ok {
+			return stripIdentityPrefix(t, s), nil
+		}
+		if tag, ok := v.(cborTagValue); ok {
+			switch tag.num {
+			case cborTagEnumeration, cborTagIdentityref:
+				//Only members of the kind given by the tag may match,
+				//so an identity SID isn't taken for an integer
+				for _, member := range t.Typs() {
+					switch member.(type) {
+					case schema.Enumeration:
+						if tag.num != cborTagEnumeration {
+							continue
+						}
+					case schema.Identityref:
+						if tag.num != cborTagIdentityref {
+							continue
+						}
+					default:
+						continue
+					}
+					s, err := u.scalar(member, tag.val)
+					if err == nil {
+						return s, nil
+					}
+				}
+				return "", newCBORTypeError(u.path, "Invalid union value")
+			}
+		}
+	}
+
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case uint64:
+		return strconv.FormatUint(val, 10), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case nil:
+		return "", nil
+	case []byte:
+		return base64.StdEncoding.EncodeToString(val), nil
+	case cborTagValue:
+		if frac, ok := val.val.([]interface{}); ok &&
+			val.num == cborTagDecimal && len(frac) == 2 {
+			exp, eok := cborInt(frac[0])
+			mant, mok := cborInt(frac[1])
+			if eok && mok {
+				return decimalString(exp, mant), nil
+			}
+		}
+	}
+	return "", newCBORTypeError(u.path, "Invalid CBOR value")
+}
+
+func (u *cborUnserialized) values() ([]string, error) {
+	typ := u.sn.Type()
+	items, ok := u.val.([]interface{})
+	if !ok {
+		val, err := u.scalar(typ, u.val)
+		if err != nil {
+			return nil, err
+		}
+		return []string{val}, nil
+	}
+	vals := make([]string, 0, len(items))
+	for _, item := range items {
+		val, err := u.scalar(typ, item)
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+	}
+	return vals, nil
+}
+
+// resolveMember resolves the key of a member of u to the name of a child of
+// its schema, and the child's frame.
+func (u *cborUnserialized) resolveMember(sn yang.Node, key interface{}) (string, cborFrame, error) {
+	var name string
+	var sid uint64
+	bySID := true
+	switch k := key.(type) {
+	case string:
+		name, bySID = k, false
+	case uint64, int64:
+		delta, _ := cborInt(k)
+		if !u.frame.hasSID {
+			return "", cborFrame{}, newCBORTypeError(u.path,
+				fmt.Sprintf("SID delta %d without a parent SID", delta))
+		}
+		sid = u.frame.sid + uint64(delta)
+	case cborTagValue:
+		abs, ok := k.val.(uint64)
+		if k.num != cborTagSID || !ok {
+			return "", cborFrame{}, newCBORTypeError(u.path, "Invalid member key")
+		}
+		sid = abs
+	default:
+		return "", cborFrame{}, newCBORTypeError(u.path, "Invalid member key")
+	}
+	if bySID {
+		path, ok := u.dec.sids.dataPath(sid)
+		if !ok {
+			return "", cborFrame{}, newCBORTypeError(u.path,
+				fmt.Sprintf("Unknown SID %d", sid))
+		}
+		name = path[strings.LastIndex(path, "/")+1:]
+	}
+
+	local := jsonLocalName(name)
+	csn := sn.Child(local)
+	if csn != nil && local != name &&
+		name[:len(name)-len(local)-1] != csn.Module() {
+		csn = nil
+	}
+	if csn == nil {
+		err := mgmterror.NewUnknownElementApplicationError(local)
+		err.Path = pathutil.Pathstr(u.path)
+		if parent, ok := sn.(schema.Node); ok {
+			return "", cborFrame{}, schema.WithSuggestions(err,
+				schema.SuggestChild(parent, local))
+		}
+		return "", cborFrame{}, err
+	}
+	frame := cborFrame{
+		path:   sidStep(u.frame.path, u.frame.module, csn.Module(), local),
+		module: csn.Module(),
+	}
+	frame.sid, frame.hasSID = u.dec.sids.dataSID(frame.path)
+	if bySID && (!frame.hasSID || frame.sid != sid) {
+		return "", cborFrame{}, newCBORTypeError(u.path,
+			fmt.Sprintf("SID %d is not a child of %s", sid, u.frame.path))
+	}
+	return local, frame, nil
+}
+
+func (u *cborUnserialized) unserializedChildren(sn yang.Node) ([]unserialized, error) {
+	if u.val == nil {
+		return nil, nil
+	}
+	if l, ok := sn.(schema.List); ok {
+		return u.listEntries(l)
+	}
+	members, ok := u.val.(cborMapValue)
+	if !ok {
+		return nil, newCBORTypeError(u.path, "Map expected")
+	}
+	children := make([]unserialized, 0, len(members))
+	for _, m := range members {
+		name, frame, err := u.resolveMember(sn, m.key)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, &cborUnserialized{
+			dec:    u.dec,
+			member: name,
+			val:    m.val,
+			sn:     sn.Child(name),
+			path:   pathutil.CopyAppend(u.path, name),
+			frame:  frame,
+		})
+	}
+	return children, nil
+}
+
+// listEntries returns the entries of a list, named by their key. The
+// members of an entry are relative to the list's SID.
+func (u *cborUnserialized) listEntries(sn schema.List) ([]unserialized, error) {
+	items, ok := u.val.([]interface{})
+	if !ok {
+		return nil, newCBORTypeError(u.path, "Array expected")
+	}
+	keyname := sn.Keys()[0]
+	entry := sn.Child(keyname)
+	entries := make([]unserialized, 0, len(items))
+	for _, item := range items {
+		members, ok := item.(cborMapValue)
+		if !ok {
+			return nil, newCBORTypeError(u.path, "Map expected")
+		}
+		var keyval string
+		found := false
+		for _, m := range members {
+			name, _, err := u.resolveMember(entry, m.key)
+			if err != nil {
+				return nil, err
+			}
+			if name != keyname {
+				continue
+			}
+			key := &cborUnserialized{dec: u.dec, sn: entry.Child(keyname),
+				path: pathutil.CopyAppend(u.path, keyname)}
+			if keyval, err = key.scalar(key.sn.Type(), m.val); err != nil {
+				return nil, err
+			}
+			found = true
+			break
+		}
+		if !found {
+			return nil, yang.NewMissingKeyError([]string{sn.Name(), keyname})
+		}
+		entries = append(entries, &cborUnserialized{
+			dec:    u.dec,
+			member: keyval,
+			val:    members,
+			sn:     entry,
+			path:   pathutil.CopyAppend(u.path, keyval),
+			frame:  u.frame,
+		})
+	}
+	return entries, nil
+}
+
+// cborDataTree converts a CBOR document to a data tree, validated against
+// the schema.
+func cborDataTree(sn schema.Node, input []byte, sids *SIDMap) (datanode.DataNode, error) {
+	r := &cborReader{buf: input}
+	doc, err := r.read()
+	if err == nil && r.pos != len(input) {
+		err = fmt.Errorf("Unexpected data after CBOR document")
+	}
+	if err != nil {
+		merr := mgmterror.NewMalformedMessageError()
+		merr.Message = err.Error()
+		return nil, merr
+	}
+	top := &cborUnserialized{
+		dec:    &cborDecoder{sids: sids, root: sn},
+		member: "root",
+		val:    doc,
+		sn:     sn,
+		path:   []string{},
+		frame:  cborFrame{hasSID: true},
+	}
+	return unserializedDataTree(top, sn)
+}
+
+// UnmarshalCBOR creates a tree from a YANG-CBOR document. The SIDs are
+// only needed if the document is keyed by SID.
+func UnmarshalCBOR(schemaRoot schema.Node, cborInput []byte, sids *SIDMap) (Node, error) {
+	root := NewNode(data.New("root"), data.New("root"), schemaRoot, nil, 0)
+	if root == nil {
+		err := mgmterror.NewOperationFailedApplicationError()
+		err.Message = "Invalid schema provided"
+		return nil, err
+	}
+
+	if err := UnmarshalCBORIntoNode(root, cborInput, sids); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// UnmarshalCBORIntoNode merges a YANG-CBOR document into ut.
+func UnmarshalCBORIntoNode(ut Node, cborInput []byte, sids *SIDMap) error {
+	datatree, err := cborDataTree(ut.GetSchema(), cborInput, sids)
+	if err != nil {
+		return err
+	}
+	return yangDataIntoTree(ut, datatree)
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const cborSchema = `
+	identity colour;
+	identity blue {
+		base colour;
+	}
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf flag {
+			type empty;
+		}
+		leaf speed {
+			type enumeration {
+				enum slow;
+				enum fast {
+					value 10;
+				}
+			}
+		}
+		leaf ratio {
+			type decimal64 {
+				fraction-digits 2;
+			}
+		}
+		leaf tint {
+			type union {
+				type uint32;
+				type identityref {
+					base colour;
+				}
+			}
+		}
+		leaf-list tags {
+			type string;
+			ordered-by user;
+		}
+		list rule {
+			key id;
+			leaf id {
+				type uint8;
+			}
+			leaf action {
+				type string;
+			}
+		}
+	}`
+
+const cborSIDFile = `{"ietf-sid-file:sid-file":{
+	"module-name":"test-union",
+	"item":[
+		{"namespace":"module","identifier":"test-union","sid":"60000"},
+		{"namespace":"data","identifier":"/test-union:top","sid":"60001"},
+		{"namespace":"data","identifier":"/test-union:top/name","sid":"60002"},
+		{"namespace":"data","identifier":"/test-union:top/flag","sid":"60003"},
+		{"namespace":"data","identifier":"/test-union:top/speed","sid":"60004"},
+		{"namespace":"data","identifier":"/test-union:top/ratio","sid":"60005"},
+		{"namespace":"data","identifier":"/test-union:top/tags","sid":"60006"},
+		{"namespace":"data","identifier":"/test-union:top/rule","sid":"60007"},
+		{"namespace":"data","identifier":"/test-union:top/rule/id","sid":"60008"},
+		{"namespace":"data","identifier":"/test-union:top/rule/action","sid":"60009"},
+		{"namespace":"data","identifier":"/test-union:top/tint","sid":"60010"},
+		{"namespace":"identity","identifier":"colour","sid":"60011"},
+		{"namespace":"identity","identifier":"blue","sid":"60012"}
+	]}}`
+
+func newCBORTestSIDs(t *testing.T) *SIDMap {
+	sids := NewSIDMap()
+	if err := sids.Load(strings.NewReader(cborSIDFile)); err != nil {
+		t.Fatalf("Unexpected SID file error: %s", err)
+	}
+	return sids
+}
+
+func TestCBOREncoding(t *testing.T) {
+	sch := newTestSchema(t, cborSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "x")
+
+	byName := []byte("\xbf\x6etest-union:top\xbf\x64name\x61x\xff\xff")
+	if actual := root.ToCBOR(); !bytes.Equal(actual, byName) {
+		t.Errorf("Unexpected CBOR encoding\n   expect=%x\n   actual=%x",
+			byName, actual)
+	}
+
+	bySID := []byte("\xbf\x19\xea\x61\xbf\x01\x61x\xff\xff")
+	if actual := root.ToCBOR(SIDs(newCBORTestSIDs(t))); !bytes.Equal(actual, bySID) {
+		t.Errorf("Unexpected SID encoding\n   expect=%x\n   actual=%x",
+			bySID, actual)
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	sch := newTestSchema(t, cborSchema)
+	sids := newCBORTestSIDs(t)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "x")
+	mustSet(t, root, "top", "flag")
+	mustSet(t, root, "top", "speed", "fast")
+	mustSet(t, root, "top", "ratio", "1.50")
+	mustSet(t, root, "top", "tint", "blue")
+	mustSet(t, root, "top", "tags", "b")
+	mustSet(t, root, "top", "tags", "a")
+	mustSet(t, root, "top", "rule", "2", "action", "drop")
+	mustSet(t, root, "top", "rule", "1")
+	expected := string(root.ToRFC7951())
+
+	for _, test := range []struct {
+		name    string
+		options []UnionOption
+	}{
+		{"names", nil},
+		{"SIDs", []UnionOption{SIDs(sids)}},
+	} {
+		decoded, err := UnmarshalCBOR(sch, root.ToCBOR(test.options...), sids)
+		if err != nil {
+			t.Errorf("Unexpected error decoding by %s: %s", test.name, err)
+			continue
+		}
+		if actual := string(decoded.ToRFC7951()); actual != expected {
+			t.Errorf("CBOR keyed by %s did not round trip\n"+
+				"   expect=%s\n   actual=%s", test.name, expected, actual)
+		}
+	}
+}
+
+func TestCBORDecodeChunkedString(t *testing.T) {
+	sch := newTestSchema(t, cborSchema)
+	input := "\xbf\x6etest-union:top\xbf\x64name" +
+		"\x7f\x61x\x61y\xff\xff\xff"
+	root, err := UnmarshalCBOR(sch, []byte(input), newCBORTestSIDs(t))
+	if err != nil {
+		t.Fatalf("Unexpected error decoding chunked string: %s", err)
+	}
+	assertTreeMatchesJson(t, root, `{"top":{"name":"xy"}}`)
+}
+
+func TestCBORDecodeErrors(t *testing.T) {
+	sch := newTestSchema(t, cborSchema)
+	sids := newCBORTestSIDs(t)
+	for _, test := range []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{"unknown SID", "\xbf\x19\xea\x61\xbf\x18\x20\x61x\xff\xff", "Unknown SID"},
+		{"unknown name", "\xbf\x6etest-union:top\xbf\x64nmae\x61x\xff\xff",
+			"Did you mean: name?"},
+		{"bad enumeration", "\xbf\x6etest-union:top\xbf\x65speed\x03\xff\xff",
+			"Unknown enumeration value 3"},
+		{"truncated", "\xbf\x6etest-union:top\xbf", "Unexpected end"},
+		{"nested chunk", "\xbf\x6etest-union:top\xbf\x64name" +
+			"\x7f\x7f\x61x\xff\xff\xff\xff", "Invalid CBOR string chunk"},
+		{"bytes chunk", "\xbf\x6etest-union:top\xbf\x64name" +
+			"\x7f\x41x\xff\xff\xff", "Invalid CBOR string chunk"},
+		{"deep nesting", strings.Repeat("\x81", 1000) + "\x00",
+			"nested too deeply"},
+	} {
+		_, err := UnmarshalCBOR(sch, []byte(test.input), sids)
+		if err == nil {
+			t.Errorf("Unexpected success decoding %s", test.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("Unexpected error decoding %s\n   expect=%s\n   actual=%s",
+				test.name, test.expect, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"sort"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// schemaChildren returns the children that can follow a node in a path,
+// the contents of choices and cases taking their place.
+func schemaChildren(sch schema.Node) []schema.Node {
+	var out []schema.Node
+	for _, ch := range sch.Children() {
+		sn, ok := ch.(schema.Node)
+		if !ok || sn.Status() == yang.Obsolete {
+			continue
+		}
+		switch sn.(type) {
+		case schema.Choice, schema.Case:
+			out = append(out, schemaChildren(sn)...)
+		default:
+			out = append(out, sn)
+		}
+	}
+	return out
+}
+
+// completionFor describes the schema node sch at path, with ch the data
+// node if it exists.
+func (n *node) completionFor(
+	auth Auther,
+	sch schema.Node,
+	ch Node,
+	path []string,
+) *schema.Completion {
+	c := schema.GetCompletion(sch)
+	switch sch.(type) {
+	case schema.Leaf, schema.LeafList:
+		c.Candidates = n.valueCandidates(auth, sch, path)
+	}
+	if ch == nil {
+		return c
+	}
+	c.Exists = !ch.def()
+	switch sch.(type) {
+	case schema.Leaf, schema.LeafList, schema.List:
+	default:
+		return c
+	}
+	if c.Secret && !authorize(auth, path, "secrets") {
+		return c
+	}
+	for _, v := range ch.SortedChildren() {
+		if v.def() || !authorize(auth, pathutil.CopyAppend(path, v.Name()), "read") {
+			continue
+		}
+		c.Instances = append(c.Instances, v.Name())
+	}
+	return c
+}
+
+// GetCompletion describes what can follow path: the values of a leaf or
+// leaf-list, the entries of a list, or else the node's children, along
+// with the existing data and, for leafrefs and identityrefs, the values
+// that may be set. The path need not exist in the data.
+func (n *node) GetCompletion(auth Auther, path []string) ([]*schema.Completion, error) {
+	if !authorize(auth, path, "read") {
+		return nil, autherr
+	}
+	sch := schema.Descendant(n.schema, path)
+	if sch == nil {
+		return nil, yang.NewNodeNotExistsError(path)
+	}
+	var d Node
+	if desc, err := n.descendant(path, make([]string, 0, len(path))); err == nil {
+		d = desc
+	}
+
+	switch sch.(type) {
+	case schema.Leaf, schema.LeafList, schema.List:
+		return []*schema.Completion{n.completionFor(auth, sch, d, path)}, nil
+	}
+
+	var out []*schema.Completion
+	keys := make(map[string]bool)
+	if entry, ok := sch.(schema.ListEntry); ok {
+		for _, key := range entry.Keys() {
+			keys[key] = true
+		}
+	}
+	for _, chs := range schemaChildren(sch) {
+		if keys[chs.Name()] || !chs.Config() {
+			continue
+		}
+		chPath := pathutil.CopyAppend(path, chs.Name())
+		if !authorize(auth, chPath, "read") {
+			continue
+		}
+		var ch Node
+		if d != nil {
+			ch = d.Child(chs.Name())
+		}
+		out = append(out, n.completionFor(auth, chs, ch, chPath))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danos/config/auth"
+	"github.com/danos/config/data"
+)
+
+const completionSchema = `
+	container top {
+		leaf name {
+			type string {
+				length 1..8;
+			}
+			configd:help "Name";
+		}
+		leaf mtu {
+			type uint16 {
+				range 68..9000;
+			}
+			default 1500;
+		}
+		leaf mode {
+			type enumeration {
+				enum fast;
+				enum slow;
+			}
+			mandatory true;
+		}
+		leaf-list tags {
+			type string;
+		}
+		list entry {
+			key id;
+			leaf id {
+				type string;
+				configd:secret true;
+			}
+			leaf value {
+				type string;
+			}
+		}
+	}`
+
+func newCompletionTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, completionSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "x")
+	mustSet(t, root, "top", "mode", "fast")
+	mustSet(t, root, "top", "tags", "a")
+	mustSet(t, root, "top", "tags", "b")
+	mustSet(t, root, "top", "entry", "one")
+	return root
+}
+
+func TestGetCompletionChildren(t *testing.T) {
+	root := newCompletionTestTree(t)
+	comps, err := root.GetCompletion(nil, []string{"top"})
+	if err != nil {
+		t.Fatalf("Unexpected completion error: %s", err)
+	}
+	var names []string
+	for _, c := range comps {
+		names = append(names, c.Name)
+	}
+	expect := []string{"entry", "mode", "mtu", "name", "tags"}
+	if !reflect.DeepEqual(names, expect) {
+		t.Fatalf("Unexpected children\n   expect=%v\n   actual=%v", expect, names)
+	}
+
+	entry, mode, mtu, name := comps[0], comps[1], comps[2], comps[3]
+	if entry.Kind != "list" || !entry.Secret ||
+		!reflect.DeepEqual(entry.Keys, []string{"id"}) ||
+		!reflect.DeepEqual(entry.Instances, []string{"one"}) {
+		t.Errorf("Unexpected list completion: %+v", entry)
+	}
+	if mode.Kind != "leaf" || !mode.Mandatory || mode.Type.Name != "enumeration" ||
+		len(mode.Type.Values) != 2 {
+		t.Errorf("Unexpected enumeration completion: %+v", mode)
+	}
+	if mtu.Exists || mtu.Type.Name != "uint16" ||
+		!reflect.DeepEqual(mtu.Type.Ranges, []string{"68..9000"}) ||
+		!reflect.DeepEqual(mtu.Default, []string{"1500"}) {
+		t.Errorf("Unexpected default completion: %+v", mtu)
+	}
+	if !name.Exists || name.Help != "Name" ||
+		!reflect.DeepEqual(name.Type.Lengths, []string{"1..8"}) ||
+		!reflect.DeepEqual(name.Instances, []string{"x"}) {
+		t.Errorf("Unexpected leaf completion: %+v", name)
+	}
+}
+
+func TestGetCompletionValues(t *testing.T) {
+	root := newCompletionTestTree(t)
+	comps, err := root.GetCompletion(nil, []string{"top", "tags"})
+	if err != nil {
+		t.Fatalf("Unexpected completion error: %s", err)
+	}
+	if len(comps) != 1 || comps[0].Kind != "leaf-list" ||
+		!reflect.DeepEqual(comps[0].Instances, []string{"a", "b"}) {
+		t.Errorf("Unexpected leaf-list completion: %+v", comps)
+	}
+
+	// Paths not in the data are completed from the schema
+	comps, err = root.GetCompletion(nil, []string{"top", "entry", "two"})
+	if err != nil {
+		t.Fatalf("Unexpected completion error: %s", err)
+	}
+	if len(comps) != 1 || comps[0].Name != "value" || comps[0].Exists {
+		t.Errorf("Unexpected list entry completion: %+v", comps)
+	}
+
+	if _, err := root.GetCompletion(nil, []string{"top", "bogus"}); err == nil {
+		t.Errorf("Unexpected completion of unknown node")
+	}
+}
+
+func TestGetCompletionSecret(t *testing.T) {
+	root := newCompletionTestTree(t)
+	auther := newTestAuther(
+		auth.NewTestAuther(
+			auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+		), false)
+	comps, err := root.GetCompletion(auther, []string{"top", "entry"})
+	if err != nil {
+		t.Fatalf("Unexpected completion error: %s", err)
+	}
+	if len(comps) != 1 || !comps[0].Exists || len(comps[0].Instances) != 0 {
+		t.Errorf("Unexpected secret completion: %+v", comps)
+	}
+}
+
+const leafrefCompletionSchema = `
+	identity speed;
+	identity fast {
+		base speed;
+	}
+	identity slow {
+		base speed;
+	}
+	container interfaces {
+		list interface {
+			key name;
+			leaf name {
+				type string;
+			}
+			leaf-list address {
+				type string;
+			}
+		}
+	}
+	container routing {
+		leaf interface {
+			type leafref {
+				path "/interfaces/interface/name";
+			}
+		}
+		leaf source {
+			type leafref {
+				path "../../interfaces/interface/address";
+			}
+		}
+		leaf speed {
+			type identityref {
+				base speed;
+			}
+		}
+	}`
+
+func TestGetCompletionCandidates(t *testing.T) {
+	sch := newTestSchema(t, leafrefCompletionSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "interfaces", "interface", "dp0s2", "address", "10.0.0.2")
+	mustSet(t, root, "interfaces", "interface", "dp0s10", "address", "10.0.0.1")
+	mustSet(t, root, "interfaces", "interface", "dp0s10", "address", "10.0.0.2")
+
+	for _, test := range []struct {
+		path   []string
+		expect []string
+	}{
+		{[]string{"routing", "interface"}, []string{"dp0s2", "dp0s10"}},
+		{[]string{"routing", "source"}, []string{"10.0.0.1", "10.0.0.2"}},
+		{[]string{"routing", "speed"}, []string{"fast", "slow"}},
+	} {
+		comps, err := root.GetCompletion(nil, test.path)
+		if err != nil {
+			t.Fatalf("Unexpected completion error for %v: %s", test.path, err)
+		}
+		if len(comps) != 1 || !reflect.DeepEqual(comps[0].Candidates, test.expect) {
+			t.Errorf("Unexpected candidates for %v\n   expect=%v\n   actual=%+v",
+				test.path, test.expect, comps)
+		}
+	}
+}
@@ -18,7 +18,7 @@ type Container struct {
 }
 
 func (n *Container) serialize(b Serializer, cpath []string, lvl int, opts *unionOptions) {
-	empty := n.serializeIsEmpty(opts.includeDefaults)
+	empty := n.serializeIsEmpty(opts.reportDefaults()) || opts.elided(lvl+1)
 	b.BeginContainer(n, empty, lvl)
 	if empty {
 		b.EndContainer(n, empty, lvl)
@@ -0,0 +1,102 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+)
+
+// Copy and rename of list entries, as used by the CLI's copy and rename
+// commands. The entry's whole subtree is duplicated under the new key;
+// the new key is validated when it is set.
+
+func newNotListEntryError(path []string) error {
+	err := mgmterror.NewOperationFailedApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = "Not a list entry"
+	return err
+}
+
+// copyEntry creates the entry newKey as a copy of the entry at path,
+// returning the new entry's path.
+func (n *node) copyEntry(auth Auther, path []string, newKey string) ([]string, error) {
+	if len(path) < 2 {
+		return nil, newNotListEntryError(path)
+	}
+	parentPath := path[:len(path)-1]
+	sch, ok := schema.Descendant(n.schema, parentPath).(schema.List)
+	if !ok {
+		return nil, newNotListEntryError(path)
+	}
+	dst := pathutil.CopyAppend(parentPath, newKey)
+	if !n.configExists(path) {
+		return nil, newDataMissingError(path)
+	}
+	if n.configExists(dst) {
+		return nil, newDataExistsError(dst)
+	}
+	if !authorize(auth, dst, "create") {
+		return nil, autherr
+	}
+
+	src, err := n.Descendant(auth, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.Set(auth, dst); err != nil {
+		return nil, err
+	}
+	key := sch.Keys()[0]
+	for _, ch := range src.MergeWithoutDefaults().Children() {
+		if ch.Name() == key {
+			continue
+		}
+		for _, p := range collectDataPaths(
+			ch, pathutil.CopyAppend(dst, ch.Name()), nil) {
+			if n.configExists(p) {
+				continue
+			}
+			if err := n.Set(auth, p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return dst, nil
+}
+
+// Copy creates the list entry newKey with the contents of the entry at
+// path. In an ordered-by user list the copy is placed after the original.
+func (n *node) Copy(auth Auther, path []string, newKey string) error {
+	return n.withRollback(func() error {
+		dst, err := n.copyEntry(auth, path, newKey)
+		if err != nil {
+			return err
+		}
+		if schema.Descendant(n.schema, path[:len(path)-1]).OrdBy() != "user" {
+			return nil
+		}
+		return n.positionEntry(auth, dst, PositionAfter, path[len(path)-1])
+	})
+}
+
+// Rename changes the key of the list entry at path to newKey, keeping its
+// contents and, in an ordered-by user list, its position.
+func (n *node) Rename(auth Auther, path []string, newKey string) error {
+	return n.withRollback(func() error {
+		dst, err := n.copyEntry(auth, path, newKey)
+		if err != nil {
+			return err
+		}
+		if schema.Descendant(n.schema, path[:len(path)-1]).OrdBy() == "user" {
+			err = n.positionEntry(auth, dst, PositionBefore, path[len(path)-1])
+			if err != nil {
+				return err
+			}
+		}
+		return n.Delete(auth, path, DontCheckAuth)
+	})
+}
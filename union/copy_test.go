@@ -0,0 +1,116 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/auth"
+	"github.com/danos/config/data"
+)
+
+const copyRuleSchema = `
+	container rules {
+		list rule {
+			key number;
+			leaf number {
+				type uint8;
+			}
+			leaf action {
+				type string;
+			}
+			leaf-list ports {
+				type string;
+				ordered-by user;
+			}
+		}
+	}`
+
+func newCopyTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, copyRuleSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	for _, path := range [][]string{
+		{"rules", "rule", "10", "action", "accept"},
+		{"rules", "rule", "10", "ports", "b"},
+		{"rules", "rule", "10", "ports", "a"},
+	} {
+		mustSet(t, root, path...)
+	}
+	return root
+}
+
+func TestCopy(t *testing.T) {
+	root := newCopyTestTree(t)
+	if err := root.Copy(nil, []string{"rules", "rule", "10"}, "20"); err != nil {
+		t.Fatalf("Unexpected copy error: %s", err)
+	}
+	checkEditConfigResult(t, root, `{"rules":{"rule":[`+
+		`{"number":10,"action":"accept","ports":["b","a"]},`+
+		`{"number":20,"action":"accept","ports":["b","a"]}]}}`)
+
+	for _, test := range []struct {
+		path   []string
+		newKey string
+	}{
+		{[]string{"rules", "rule", "30"}, "40"},
+		{[]string{"rules", "rule", "10"}, "20"},
+		{[]string{"rules", "rule", "10"}, "300"},
+		{[]string{"rules", "rule", "10", "action"}, "drop"},
+	} {
+		if err := root.Copy(nil, test.path, test.newKey); err == nil {
+			t.Errorf("Unexpected copy success for %v to %s",
+				test.path, test.newKey)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	root := newCopyTestTree(t)
+	if err := root.Rename(nil, []string{"rules", "rule", "10"}, "5"); err != nil {
+		t.Fatalf("Unexpected rename error: %s", err)
+	}
+	checkEditConfigResult(t, root, `{"rules":{"rule":[`+
+		`{"number":5,"action":"accept","ports":["b","a"]}]}}`)
+}
+
+func TestRenameOrderedByUser(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	mustSet(t, root, "top", "entry", "two", "value", "2")
+	if err := root.Rename(nil, []string{"top", "entry", "one"}, "three"); err != nil {
+		t.Fatalf("Unexpected rename error: %s", err)
+	}
+	if err := root.Copy(nil, []string{"top", "entry", "three"}, "four"); err != nil {
+		t.Fatalf("Unexpected copy error: %s", err)
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"three","value":"1"},`+
+		`{"id":"four","value":"1"},{"id":"two","value":"2"}],`+
+		`"name":"orig","tags":["a","b"]}}`)
+}
+
+func TestRenameAuth(t *testing.T) {
+	root := newCopyTestTree(t)
+	auther := newTestAuther(
+		auth.NewTestAuther(
+			auth.NewTestRule(auth.Deny, auth.P_DELETE, "/rules/rule/10"),
+			auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+		), true)
+	err := root.Rename(auther, []string{"rules", "rule", "10"}, "20")
+	if err == nil {
+		t.Fatalf("Unexpected rename success without delete authorization")
+	}
+	// The copy is rolled back
+	checkEditConfigResult(t, root, `{"rules":{"rule":[`+
+		`{"number":10,"action":"accept","ports":["b","a"]}]}}`)
+
+	auther = newTestAuther(
+		auth.NewTestAuther(
+			auth.NewTestRule(auth.Deny, auth.P_CREATE, "/rules/rule/20"),
+			auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+		), true)
+	err = root.Copy(auther, []string{"rules", "rule", "10"}, "20")
+	if err == nil {
+		t.Fatalf("Unexpected copy success without create authorization")
+	}
+}
@@ -0,0 +1,542 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// Streaming RFC 7951 decoding. UnmarshalRFC7951 builds the whole document
+// before inserting it; DecodeRFC7951 instead reads the document a token at
+// a time, setting each value in the tree as soon as it is read, so only
+// the tree itself grows with the size of the input. The one exception is
+// a list entry whose key is not its first member: the members before the
+// key are held until the key is read.
+
+// DecodeError reports where in the document decoding failed, as an RFC 6901
+// JSON pointer to the member or array element at fault.
+type DecodeError struct {
+	Pointer string
+	Err     error
+}
+
+func (e *DecodeError) Error() string {
+	if e.Pointer == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+var jsonPointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+type streamDecoder struct {
+	ut Node
+	//pointer holds the reference tokens of the current position
+	pointer []string
+}
+
+// jsonObject collects the metadata of an object, which is applied once
+// the object's data has been set.
+type jsonObject struct {
+	path    []string
+	targets []annotationTarget
+	//leafLists are the values read for each leaf-list, and leafListAnns
+	//their metadata, to be matched up by position
+	leafLists    map[string][]string
+	leafListAnns map[string][]interface{}
+}
+
+func (d *streamDecoder) push(token string) {
+	d.pointer = append(d.pointer, token)
+}
+
+func (d *streamDecoder) pop() {
+	d.pointer = d.pointer[:len(d.pointer)-1]
+}
+
+// fail records the position of the first error.
+func (d *streamDecoder) fail(err error) error {
+	if _, ok := err.(*DecodeError); ok {
+		return err
+	}
+	var b strings.Builder
+	for _, tok := range d.pointer {
+		b.WriteByte('/')
+		b.WriteString(jsonPointerEscaper.Replace(tok))
+	}
+	return &DecodeError{Pointer: b.String(), Err: err}
+}
+
+func newMalformedJSONError(err error) error {
+	merr := mgmterror.NewMalformedMessageError()
+	merr.Message = err.Error()
+	return merr
+}
+
+func newJSONTypeError(path []string, msg string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = msg
+	return err
+}
+
+func (d *streamDecoder) token(dec *json.Decoder) (json.Token, error) {
+	tok, err := dec.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, d.fail(newMalformedJSONError(err))
+	}
+	return tok, nil
+}
+
+func (d *streamDecoder) expect(dec *json.Decoder, delim json.Delim, path []string) error {
+	tok, err := d.token(dec)
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return d.fail(newJSONTypeError(path,
+			fmt.Sprintf("Expected '%s', found %v", delim, tok)))
+	}
+	return nil
+}
+
+func (d *streamDecoder) set(path []string) error {
+	if err := d.ut.Set(nil, path); err != nil {
+		return d.fail(err)
+	}
+	return nil
+}
+
+// create sets a presence container or list entry, which may already exist
+// when merging into a tree.
+func (d *streamDecoder) create(path []string) error {
+	if d.ut.Exists(nil, path) == nil {
+		return nil
+	}
+	return d.set(path)
+}
+
+// decodeObject reads an object holding the children of sn.
+func (d *streamDecoder) decodeObject(
+	dec *json.Decoder,
+	sn schema.Node,
+	path []string,
+) error {
+	if err := d.expect(dec, json.Delim('{'), path); err != nil {
+		return err
+	}
+	obj := &jsonObject{path: path}
+	for dec.More() {
+		tok, err := d.token(dec)
+		if err != nil {
+			return err
+		}
+		member := tok.(string)
+		d.push(member)
+		if err := d.decodeMember(dec, sn, path, member, obj); err != nil {
+			return err
+		}
+		d.pop()
+	}
+	if err := d.expect(dec, json.Delim('}'), path); err != nil {
+		return err
+	}
+	return d.applyObjectAnnotations(obj)
+}
+
+// decodeMember reads the value of member of the object at path.
+func (d *streamDecoder) decodeMember(
+	dec *json.Decoder,
+	sn schema.Node,
+	path []string,
+	member string,
+	obj *jsonObject,
+) error {
+	if strings.HasPrefix(member, "@") {
+		return d.decodeAnnotation(dec, sn, path, member, obj)
+	}
+
+	name := jsonLocalName(member)
+	csn := sn.SchemaChild(name)
+	if csn != nil && name != member && member[:len(member)-len(name)-1] != csn.Module() {
+		csn = nil
+	}
+	if csn == nil {
+		err := mgmterror.NewUnknownElementApplicationError(name)
+		err.Path = pathutil.Pathstr(path)
+		return d.fail(schema.WithSuggestions(err, schema.SuggestChild(sn, name)))
+	}
+	cpath := pathutil.CopyAppend(path, name)
+
+	switch v := csn.(type) {
+	case schema.Container:
+		if v.Presence() {
+			if err := d.create(cpath); err != nil {
+				return err
+			}
+		}
+		return d.decodeObject(dec, v, cpath)
+	case schema.List:
+		return d.decodeList(dec, v, cpath)
+	case schema.Leaf:
+		val, empty, err := d.decodeValue(dec, v, cpath)
+		if err != nil {
+			return err
+		}
+		if empty {
+			return d.set(cpath)
+		}
+		return d.set(pathutil.CopyAppend(cpath, val))
+	case schema.LeafList:
+		vals, err := d.decodeLeafList(dec, v, cpath)
+		if err != nil {
+			return err
+		}
+		if obj.leafLists == nil {
+			obj.leafLists = make(map[string][]string)
+		}
+		obj.leafLists[name] = vals
+		return nil
+	default:
+		return d.fail(yang.NewSchemaMismatchError(name, path))
+	}
+}
+
+// decodeValue reads the value of a leaf, leaf-list or list key. The empty
+// type is encoded as [null], for which empty is returned.
+func (d *streamDecoder) decodeValue(
+	dec *json.Decoder,
+	sn schema.Node,
+	path []string,
+) (val string, empty bool, err error) {
+	tok, err := d.token(dec)
+	if err != nil {
+		return "", false, err
+	}
+	typ, _ := sn.Type().(schema.Type)
+	_, isEmpty := typ.(schema.Empty)
+
+	if tok == json.Delim('[') {
+		if tok, err = d.token(dec); err != nil {
+			return "", false, err
+		}
+		if tok != nil || !isEmpty {
+			return "", false, d.fail(newJSONTypeError(path, "Unexpected array"))
+		}
+		if err := d.expect(dec, json.Delim(']'), path); err != nil {
+			return "", false, err
+		}
+		return "", true, nil
+	}
+	if isEmpty {
+		return "", false, d.fail(newEmptyLeafWithValue(sn.Name()))
+	}
+
+	switch v := tok.(type) {
+	case string:
+		if _, ok := typ.(schema.Boolean); ok {
+			return "", false, d.fail(
+				newJSONTypeError(path, "Boolean value must not be a string"))
+		}
+		return stripIdentityPrefix(typ, v), false, nil
+	case json.Number:
+		switch typ.(type) {
+		case schema.Integer, schema.Uinteger, schema.Decimal64, schema.Union:
+			return v.String(), false, nil
+		}
+		return "", false, d.fail(newJSONTypeError(path, "Unexpected number"))
+	case bool:
+		switch typ.(type) {
+		case schema.Boolean, schema.Union:
+			return strconv.FormatBool(v), false, nil
+		}
+		return "", false, d.fail(newJSONTypeError(path, "Unexpected boolean"))
+	default:
+		return "", false, d.fail(
+			newJSONTypeError(path, fmt.Sprintf("Unexpected %v", tok)))
+	}
+}
+
+// stripIdentityPrefix removes the module name qualifying an identity.
+func stripIdentityPrefix(typ schema.Type, val string) string {
+	switch t := typ.(type) {
+	case schema.Identityref:
+		for _, idn := range t.Identities() {
+			if val == idn.Module+":"+idn.Val {
+				return idn.Val
+			}
+		}
+	case schema.Union:
+		for _, member := range t.Typs() {
+			mt, ok := member.(schema.Type)
+			if !ok {
+				continue
+			}
+			if stripped := stripIdentityPrefix(mt, val); stripped != val {
+				return stripped
+			}
+		}
+	}
+	return val
+}
+
+func (d *streamDecoder) decodeLeafList(
+	dec *json.Decoder,
+	sn schema.LeafList,
+	path []string,
+) ([]string, error) {
+	if err := d.expect(dec, json.Delim('['), path); err != nil {
+		return nil, err
+	}
+	var vals []string
+	for i := 0; dec.More(); i++ {
+		d.push(strconv.Itoa(i))
+		val, _, err := d.decodeValue(dec, sn, path)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.set(pathutil.CopyAppend(path, val)); err != nil {
+			return nil, err
+		}
+		vals = append(vals, val)
+		d.pop()
+	}
+	return vals, d.expect(dec, json.Delim(']'), path)
+}
+
+func (d *streamDecoder) decodeList(
+	dec *json.Decoder,
+	sn schema.List,
+	path []string,
+) error {
+	if err := d.expect(dec, json.Delim('['), path); err != nil {
+		return err
+	}
+	for i := 0; dec.More(); i++ {
+		d.push(strconv.Itoa(i))
+		if err := d.decodeListEntry(dec, sn, path); err != nil {
+			return err
+		}
+		d.pop()
+	}
+	return d.expect(dec, json.Delim(']'), path)
+}
+
+type bufferedMember struct {
+	name string
+	raw  json.RawMessage
+}
+
+// decodeListEntry reads an entry of the list at path. Members before the
+// key are buffered, then replayed once the entry's path is known.
+func (d *streamDecoder) decodeListEntry(
+	dec *json.Decoder,
+	sn schema.List,
+	path []string,
+) error {
+	if err := d.expect(dec, json.Delim('{'), path); err != nil {
+		return err
+	}
+	keyname := sn.Keys()[0]
+	var esn schema.Node
+	var epath []string
+	var pending []bufferedMember
+	obj := &jsonObject{}
+
+	for dec.More() {
+		tok, err := d.token(dec)
+		if err != nil {
+			return err
+		}
+		member := tok.(string)
+		d.push(member)
+		switch {
+		case epath != nil:
+			err = d.decodeMember(dec, esn, epath, member, obj)
+		case jsonLocalName(member) == keyname:
+			//Any name gives the schema of the list's entries
+			key := sn.SchemaChild(keyname).SchemaChild(keyname)
+			var val string
+			val, _, err = d.decodeValue(dec, key, pathutil.CopyAppend(path, keyname))
+			if err != nil {
+				break
+			}
+			esn = sn.SchemaChild(val)
+			epath = pathutil.CopyAppend(path, val)
+			obj.path = epath
+			if err = d.create(epath); err != nil {
+				break
+			}
+			err = d.replay(esn, epath, pending, obj)
+			pending = nil
+		default:
+			var raw json.RawMessage
+			if err = dec.Decode(&raw); err != nil {
+				err = d.fail(newMalformedJSONError(err))
+				break
+			}
+			pending = append(pending, bufferedMember{name: member, raw: raw})
+		}
+		if err != nil {
+			return err
+		}
+		d.pop()
+	}
+	if epath == nil {
+		return d.fail(yang.NewMissingKeyError(
+			[]string{pathutil.Pathstr(path), keyname}))
+	}
+	if err := d.expect(dec, json.Delim('}'), path); err != nil {
+		return err
+	}
+	return d.applyObjectAnnotations(obj)
+}
+
+func (d *streamDecoder) replay(
+	sn schema.Node,
+	path []string,
+	pending []bufferedMember,
+	obj *jsonObject,
+) error {
+	key := d.pointer[len(d.pointer)-1]
+	d.pop()
+	for _, m := range pending {
+		dec := json.NewDecoder(bytes.NewReader(m.raw))
+		dec.UseNumber()
+		d.push(m.name)
+		if err := d.decodeMember(dec, sn, path, m.name, obj); err != nil {
+			return err
+		}
+		d.pop()
+	}
+	d.push(key)
+	return nil
+}
+
+// decodeAnnotation reads an RFC 7952 metadata member: "@" for the object
+// itself, or "@name" for its leaf or leaf-list child.
+func (d *streamDecoder) decodeAnnotation(
+	dec *json.Decoder,
+	sn schema.Node,
+	path []string,
+	member string,
+	obj *jsonObject,
+) error {
+	var val interface{}
+	if err := dec.Decode(&val); err != nil {
+		return d.fail(newMalformedJSONError(err))
+	}
+	if member == "@" {
+		anns, err := jsonAnnotationObject(path, val)
+		if err != nil {
+			return d.fail(err)
+		}
+		obj.targets = append(obj.targets, annotationTarget{path: path, anns: anns})
+		return nil
+	}
+	name := jsonLocalName(member[1:])
+	tpath := pathutil.CopyAppend(path, name)
+	switch sn.SchemaChild(name).(type) {
+	case schema.Leaf:
+		anns, err := jsonAnnotationObject(tpath, val)
+		if err != nil {
+			return d.fail(err)
+		}
+		obj.targets = append(obj.targets, annotationTarget{path: tpath, anns: anns})
+	case schema.LeafList:
+		annlist, _ := val.([]interface{})
+		if obj.leafListAnns == nil {
+			obj.leafListAnns = make(map[string][]interface{})
+		}
+		obj.leafListAnns[name] = annlist
+	}
+	return nil
+}
+
+func (d *streamDecoder) applyObjectAnnotations(obj *jsonObject) error {
+	for name, annlist := range obj.leafListAnns {
+		vals := obj.leafLists[name]
+		for i, ann := range annlist {
+			if ann == nil || i >= len(vals) {
+				continue
+			}
+			vpath := pathutil.CopyAppend(pathutil.CopyAppend(obj.path, name), vals[i])
+			anns, err := jsonAnnotationObject(vpath, ann)
+			if err != nil {
+				return d.fail(err)
+			}
+			obj.targets = append(obj.targets, annotationTarget{path: vpath, anns: anns})
+		}
+	}
+	if err := applyAnnotations(d.ut, obj.targets); err != nil {
+		return d.fail(err)
+	}
+	return nil
+}
+
+// decode reads a single document from r into the tree.
+func (d *streamDecoder) decode(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	if err := d.decodeObject(dec, d.ut.GetSchema(), []string{}); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = fmt.Errorf("Unexpected data after top-level object")
+		}
+		return d.fail(newMalformedJSONError(err))
+	}
+	return nil
+}
+
+// DecodeRFC7951 reads an RFC 7951 document from r into a new tree, as
+// UnmarshalRFC7951 does but without holding the whole document in memory.
+// Errors in the document are returned as a *DecodeError giving the
+// position of the fault. Constraints on the tree as a whole, such as
+// mandatory nodes, are checked once the document has been read.
+func DecodeRFC7951(schemaRoot schema.Node, r io.Reader) (Node, error) {
+	root := NewNode(data.New("root"), data.New("root"), schemaRoot, nil, 0)
+	if root == nil {
+		err := mgmterror.NewOperationFailedApplicationError()
+		err.Message = "Invalid schema provided"
+		return nil, err
+	}
+
+	if err := DecodeRFC7951IntoNode(root, r); err != nil {
+		return nil, err
+	}
+	if err := validateDataNode(root, schemaRoot); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// DecodeRFC7951IntoNode merges an RFC 7951 document read from r into ut.
+// Each value is validated as it is set, but the tree as a whole is left
+// for the caller to validate. Data set before an error is found is left
+// in the tree.
+func DecodeRFC7951IntoNode(ut Node, r io.Reader) error {
+	d := &streamDecoder{ut: ut}
+	return d.decode(r)
+}
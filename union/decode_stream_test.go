@@ -0,0 +1,93 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeRFC7951(t *testing.T) {
+	sch := newTestSchema(t, completionSchema)
+	input := `{"test-union:top":{` +
+		`"name":"x","mtu":1400,"mode":"fast","tags":["a","b"],` +
+		`"entry":[{"value":"v1","id":"one"},{"id":"two"}]}}`
+
+	expect, err := UnmarshalRFC7951(sch, []byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected unmarshal error: %s", err)
+	}
+	actual, err := DecodeRFC7951(sch, strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected decode error: %s", err)
+	}
+	if e, a := string(expect.ToRFC7951()), string(actual.ToRFC7951()); e != a {
+		t.Errorf("Unexpected decoded tree\n   expect=%s\n   actual=%s", e, a)
+	}
+}
+
+func TestDecodeRFC7951Annotations(t *testing.T) {
+	input := `{"test-union:top":{` +
+		`"@":{"test-union:note":"container"},` +
+		`"@tags":[null,{"test-union:note":"value"}],"tags":["a","b"],` +
+		`"name":"foo","@name":{"test-union:note":"leaf"}}}`
+
+	root, err := DecodeRFC7951(newTestSchema(t, annotationSchema),
+		strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected decode error: %s", err)
+	}
+	checkAnnotation(t, root, []string{"top"}, "test-union:note", "container")
+	checkAnnotation(t, root, []string{"top", "name"}, "test-union:note", "leaf")
+	checkAnnotation(t, root, []string{"top", "tags", "b"},
+		"test-union:note", "value")
+}
+
+func TestDecodeRFC7951Errors(t *testing.T) {
+	sch := newTestSchema(t, completionSchema)
+	for _, test := range []struct {
+		input   string
+		pointer string
+	}{
+		{`{"test-union:top":{"mtu":10}}`, "/test-union:top/mtu"},
+		{`{"test-union:top":{"mode":true}}`, "/test-union:top/mode"},
+		{`{"test-union:top":{"tags":["a",1]}}`, "/test-union:top/tags/1"},
+		{`{"test-union:top":{"entry":[{"id":"one"},{"value":"v"}]}}`,
+			"/test-union:top/entry/1"},
+		{`{"test-union:top":{"entry":[{"value":1,"id":"one"}]}}`,
+			"/test-union:top/entry/0/value"},
+		{`{"test-union:top":{"nmae":"x"}}`, "/test-union:top/nmae"},
+		{`{"other:top":{}}`, "/other:top"},
+		{`{"test-union:top":{"name":"x"`, "/test-union:top"},
+	} {
+		_, err := DecodeRFC7951(sch, strings.NewReader(test.input))
+		if err == nil {
+			t.Errorf("Unexpected success decoding %s", test.input)
+			continue
+		}
+		derr, ok := err.(*DecodeError)
+		if !ok {
+			t.Errorf("Unexpected error type decoding %s: %T", test.input, err)
+			continue
+		}
+		if derr.Pointer != test.pointer {
+			t.Errorf("Unexpected error position decoding %s\n"+
+				"   expect=%s\n   actual=%s (%s)",
+				test.input, test.pointer, derr.Pointer, err)
+		}
+	}
+}
+
+func TestDecodeRFC7951Mandatory(t *testing.T) {
+	sch := newTestSchema(t, completionSchema)
+	_, err := DecodeRFC7951(sch,
+		strings.NewReader(`{"test-union:top":{"name":"x"}}`))
+	if err == nil {
+		t.Fatalf("Unexpected success decoding without mandatory leaf")
+	}
+	if !strings.Contains(err.Error(), "mode") {
+		t.Errorf("Unexpected error for missing mandatory leaf: %s", err)
+	}
+}
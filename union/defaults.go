@@ -0,0 +1,137 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"encoding/xml"
+)
+
+// With-defaults (RFC 6243) reporting modes for the encoders.
+//
+// Schema defaults are the nodes the tree supplies because nothing is
+// configured, a node explicitly set to its default value is not one of
+// them. The modes differ in how they treat the two:
+//
+//	report-all         both are reported
+//	report-all-tagged  both are reported, and tagged as default
+//	trim               neither is reported
+//	explicit           only the explicitly set values are reported
+//
+// Explicit is the behaviour when no mode is given.
+
+type WithDefaultsMode string
+
+const (
+	WithDefaultsReportAll       WithDefaultsMode = "report-all"
+	WithDefaultsReportAllTagged WithDefaultsMode = "report-all-tagged"
+	WithDefaultsTrim            WithDefaultsMode = "trim"
+	WithDefaultsExplicit        WithDefaultsMode = "explicit"
+)
+
+const (
+	withDefaultsNamespace = "urn:ietf:params:xml:ns:netconf:default:1.0"
+	//withDefaultsAnnotation is the RFC 7952 form of the wd:default attribute
+	withDefaultsAnnotation = "ietf-netconf-with-defaults:default"
+)
+
+func WithDefaults(mode WithDefaultsMode) UnionOption {
+	return func(opts *unionOptions) {
+		opts.withDefaults = mode
+	}
+}
+
+// reportDefaults is true if schema defaults are to be reported
+func (opts *unionOptions) reportDefaults() bool {
+	switch opts.withDefaults {
+	case WithDefaultsReportAll, WithDefaultsReportAllTagged:
+		return true
+	}
+	return false
+}
+
+func (opts *unionOptions) tagDefaults() bool {
+	return opts.withDefaults == WithDefaultsReportAllTagged
+}
+
+func (opts *unionOptions) hideDefault(n Node) bool {
+	switch opts.withDefaults {
+	case WithDefaultsReportAll, WithDefaultsReportAllTagged:
+		return false
+	case WithDefaultsTrim:
+		return n.getnode().isDefaultValue()
+	}
+	return n.def()
+}
+
+// isDefaultValue is true for schema defaults, for leaves and
+// leaf-lists whose values are the schema default values, and for
+// non-presence containers holding nothing else.
+func (n *node) isDefaultValue() bool {
+	if n.def() {
+		return true
+	}
+	switch n.specialized.(type) {
+	case *Leaf, *LeafList:
+	case *Container:
+		if n.schema.HasPresence() {
+			return false
+		}
+		for _, ch := range n.SortedChildren() {
+			if !ch.getnode().isDefaultValue() {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+	defs := n.schema.DefaultChildNames()
+	vals := n.SortedChildren()
+	if len(defs) == 0 || len(defs) != len(vals) {
+		return false
+	}
+	for i, v := range vals {
+		if v.Name() != defs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// withAnnotation returns a copy of anns with the annotation added
+func withAnnotation(anns map[string]string, name, value string) map[string]string {
+	out := make(map[string]string, len(anns)+1)
+	for n, v := range anns {
+		out[n] = v
+	}
+	out[name] = value
+	return out
+}
+
+// defaultAnnotations returns anns with the with-defaults tag added
+func defaultAnnotations(anns map[string]string) map[string]string {
+	return withAnnotation(anns, withDefaultsAnnotation, "true")
+}
+
+// leafAnnotations returns the metadata to be written for a leaf or
+// leaf-list value.
+func (b *JSONWriter) leafAnnotations(n Node, tagged bool) map[string]string {
+	anns := b.nodeAnnotations(n)
+	if tagged {
+		return defaultAnnotations(anns)
+	}
+	return anns
+}
+
+// defaultAttributes adds the with-defaults default attribute when
+// tagging defaults. The encoder declares the namespace prefix.
+func (enc *XMLWriter) defaultAttributes(n Node, attrs []xml.Attr) []xml.Attr {
+	if !enc.tagDefaults || !n.getnode().isDefaultValue() {
+		return attrs
+	}
+	return append(attrs, xml.Attr{
+		Name:  xml.Name{Space: withDefaultsNamespace, Local: "default"},
+		Value: "true"})
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const withDefaultsSchema = `
+	container top {
+		leaf mtu {
+			type uint32;
+			default 1500;
+		}
+		leaf name {
+			type string;
+			default "x";
+		}
+		leaf desc {
+			type string;
+		}
+	}`
+
+func newWithDefaultsTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, withDefaultsSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "x")
+	mustSet(t, root, "top", "desc", "d")
+	return root
+}
+
+func TestWithDefaultsJSON(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	for _, test := range []struct {
+		opts     []UnionOption
+		expected string
+	}{
+		{nil, `{"top":{"desc":"d","name":"x"}}`},
+		{[]UnionOption{WithDefaults(WithDefaultsExplicit)},
+			`{"top":{"desc":"d","name":"x"}}`},
+		{[]UnionOption{WithDefaults(WithDefaultsTrim)},
+			`{"top":{"desc":"d"}}`},
+		{[]UnionOption{WithDefaults(WithDefaultsReportAll)},
+			`{"top":{"desc":"d","mtu":1500,"name":"x"}}`},
+		{[]UnionOption{IncludeDefaults},
+			`{"top":{"desc":"d","mtu":1500,"name":"x"}}`},
+		//Plain JSON carries no metadata
+		{[]UnionOption{WithDefaults(WithDefaultsReportAllTagged)},
+			`{"top":{"desc":"d","mtu":1500,"name":"x"}}`},
+	} {
+		actual := string(root.ToJSON(test.opts...))
+		if actual != test.expected {
+			t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+				test.expected, actual)
+		}
+	}
+}
+
+func TestWithDefaultsTrimContainer(t *testing.T) {
+	sch := newTestSchema(t, `
+	container top {
+		container timers {
+			leaf hold {
+				type uint32;
+				default 90;
+			}
+		}
+		leaf desc {
+			type string;
+		}
+	}`)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "timers", "hold", "90")
+	mustSet(t, root, "top", "desc", "d")
+	expected := `{"top":{"desc":"d"}}`
+	actual := string(root.ToJSON(WithDefaults(WithDefaultsTrim)))
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestWithDefaultsRFC7951Tagged(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	expected := `{"test-union:top":{"desc":"d",` +
+		`"mtu":1500,"@mtu":{"ietf-netconf-with-defaults:default":true},` +
+		`"name":"x","@name":{"ietf-netconf-with-defaults:default":true}}}`
+	actual := string(root.ToRFC7951(
+		WithDefaults(WithDefaultsReportAllTagged)))
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestWithDefaultsXMLTagged(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	out := string(root.ToXML("data", WithDefaults(WithDefaultsReportAllTagged)))
+	for _, expected := range []string{
+		`="urn:ietf:params:xml:ns:netconf:default:1.0"`,
+		`:default="true">1500</mtu>`,
+		`:default="true">x</name>`,
+		`<desc xmlns="urn:vyatta.com:test:union">d</desc>`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Missing %s in %s", expected, out)
+		}
+	}
+
+	out = string(root.ToNETCONF("data", WithDefaults(WithDefaultsTrim)))
+	if strings.Contains(out, "mtu") || strings.Contains(out, "<name") {
+		t.Errorf("Defaults not trimmed: %s", out)
+	}
+}
+
+func TestWithDefaultsShow(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	out, err := root.Show([]string{"top"},
+		WithDefaults(WithDefaultsReportAllTagged))
+	if err != nil {
+		t.Fatalf("Unexpected show error: %s", err)
+	}
+	for _, expected := range []string{
+		"mtu 1500 /* default */\n",
+		"name x /* default */\n",
+		"desc d\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Missing %q in %q", expected, out)
+		}
+	}
+}
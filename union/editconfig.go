@@ -0,0 +1,321 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// EditConfig applies a NETCONF <edit-config> payload (RFC 6241 section
+// 7.2) to the tree, honouring the nc:operation attribute on each element.
+
+const netconfBaseNamespace = "urn:ietf:params:xml:ns:netconf:base:1.0"
+
+type EditOperation string
+
+const (
+	EditMerge   EditOperation = "merge"
+	EditReplace EditOperation = "replace"
+	EditCreate  EditOperation = "create"
+	EditDelete  EditOperation = "delete"
+	EditRemove  EditOperation = "remove"
+	EditNone    EditOperation = "none"
+)
+
+type ErrorOption string
+
+const (
+	StopOnError     ErrorOption = "stop-on-error"
+	ContinueOnError ErrorOption = "continue-on-error"
+	RollbackOnError ErrorOption = "rollback-on-error"
+)
+
+func newBadOperationError(path []string, op string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = fmt.Sprintf("Invalid operation: %s", op)
+	return err
+}
+
+func newDataExistsError(path []string) error {
+	err := mgmterror.NewDataExistsError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = "Node exists"
+	return err
+}
+
+func newDataMissingError(path []string) error {
+	err := mgmterror.NewDataMissingError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = "Node does not exist"
+	return err
+}
+
+type editor struct {
+	root  *node
+	auth  Auther
+	errop ErrorOption
+	errs  []error
+}
+
+func elementOperation(
+	elem *xmlElement,
+	path []string,
+	inherited EditOperation,
+) (EditOperation, error) {
+	for _, attr := range elem.attrs {
+		if attr.Name.Space != netconfBaseNamespace ||
+			attr.Name.Local != "operation" {
+			continue
+		}
+		switch op := EditOperation(attr.Value); op {
+		case EditMerge, EditReplace, EditCreate, EditDelete, EditRemove:
+			return op, nil
+		default:
+			return "", newBadOperationError(path, attr.Value)
+		}
+	}
+	return inherited, nil
+}
+
+// configExists reports whether path is configured, defaults don't count
+func (n *node) configExists(path []string) bool {
+	return callInternalWalker(n.validateNotExistsSet, path) != nil
+}
+
+func (e *editor) exists(path []string) bool {
+	return e.root.configExists(path)
+}
+
+func (e *editor) set(path []string) error {
+	if e.exists(path) {
+		return nil
+	}
+	return e.root.Set(e.auth, path)
+}
+
+func (e *editor) remove(path []string) error {
+	if !e.exists(path) {
+		return nil
+	}
+	return e.root.Delete(e.auth, path, DontCheckAuth)
+}
+
+// elementIs reports whether elem is the element for the schema node
+// sn called name, that is it has the name and namespace of sn.
+func elementIs(elem *xmlElement, sn schema.Node, name string) bool {
+	return elem.name.Local == name && elem.name.Space == sn.Namespace()
+}
+
+// elementPath returns the path of the data node the element represents,
+// including the value for leaf and leaf-list elements, and its schema.
+func elementPath(
+	elem *xmlElement,
+	sn schema.Node,
+	parent []string,
+) ([]string, schema.Node, error) {
+	name := elem.name.Local
+	path := pathutil.CopyAppend(parent, name)
+	text := strings.TrimSpace(elem.text)
+	switch v := sn.(type) {
+	case schema.List:
+		keyname := v.Keys()[0]
+		for _, ch := range elem.children {
+			if elementIs(ch, sn, keyname) {
+				key := strings.TrimSpace(ch.text)
+				return pathutil.CopyAppend(path, key), v.SchemaChild(key), nil
+			}
+		}
+		return nil, nil, yang.NewMissingKeyError(path)
+	case schema.Leaf:
+		if _, isEmpty := v.Type().(schema.Empty); isEmpty {
+			if text != "" {
+				return nil, nil, newEmptyLeafWithValue(name)
+			}
+			return path, sn, nil
+		}
+		return pathutil.CopyAppend(path, text), sn, nil
+	case schema.LeafList:
+		return pathutil.CopyAppend(path, text), sn, nil
+	}
+	return path, sn, nil
+}
+
+func (e *editor) applyChildren(
+	elem *xmlElement,
+	sn schema.Node,
+	path []string,
+	op EditOperation,
+) error {
+	var keyname string
+	if l, ok := sn.(schema.ListEntry); ok {
+		keyname = l.Keys()[0]
+	}
+	for _, ch := range elem.children {
+		if keyname != "" && elementIs(ch, sn, keyname) {
+			continue
+		}
+		err := e.apply(ch, sn, path, op)
+		if err == nil {
+			continue
+		}
+		if e.errop != ContinueOnError {
+			return err
+		}
+		e.errs = append(e.errs, err)
+	}
+	return nil
+}
+
+func (e *editor) apply(
+	elem *xmlElement,
+	parentSchema schema.Node,
+	parent []string,
+	inherited EditOperation,
+) error {
+	name := elem.name.Local
+	sn := parentSchema.SchemaChild(name)
+	if sn == nil || sn.Namespace() != elem.name.Space {
+		err := mgmterror.NewUnknownElementApplicationError(name)
+		err.Path = pathutil.Pathstr(parent)
+		return err
+	}
+	op, err := elementOperation(elem, pathutil.CopyAppend(parent, name), inherited)
+	if err != nil {
+		return err
+	}
+	path, sn, err := elementPath(elem, sn, parent)
+	if err != nil {
+		return err
+	}
+	if sn == nil {
+		return yang.NewSchemaMismatchError(name, parent)
+	}
+
+	//Leaf values are replaced by a merge so operate
+	//on the leaf, not the value, when checking existance
+	target := path
+	if _, ok := sn.(schema.Leaf); ok && len(path) > len(parent)+1 {
+		target = path[:len(parent)+1]
+	}
+
+	switch op {
+	case EditDelete:
+		if !e.exists(target) {
+			return newDataMissingError(target)
+		}
+		return e.root.Delete(e.auth, target, DontCheckAuth)
+	case EditRemove:
+		return e.remove(target)
+	case EditCreate:
+		if e.exists(target) {
+			return newDataExistsError(target)
+		}
+	case EditReplace:
+		if err := e.remove(target); err != nil {
+			return err
+		}
+	case EditNone:
+		//Nothing is created, so every level of the
+		//configuration must already exist
+		if !e.exists(path) {
+			return newDataMissingError(path)
+		}
+		switch sn.(type) {
+		case schema.Leaf, schema.LeafList:
+			return nil
+		}
+		return e.applyChildren(elem, sn, path, op)
+	}
+
+	switch v := sn.(type) {
+	case schema.Leaf, schema.LeafList, schema.ListEntry:
+		if err := e.set(path); err != nil {
+			return err
+		}
+	case schema.Container:
+		if v.Presence() {
+			if err := e.set(path); err != nil {
+				return err
+			}
+		}
+	}
+	return e.applyChildren(elem, sn, path, op)
+}
+
+func validDefaultOperation(op EditOperation) bool {
+	switch op {
+	case EditMerge, EditReplace, EditNone:
+		return true
+	}
+	return false
+}
+
+// EditConfig applies the contents of the <config> element to the tree.
+// The default operation applies to elements without an operation
+// attribute; it is one of merge, replace or none.
+//
+// On error, stop-on-error leaves the changes made before the error in
+// place, continue-on-error carries on with the remaining elements and
+// returns all errors, and rollback-on-error reverts the whole edit.
+// The edit is a single change in the journal.
+func (n *node) EditConfig(
+	auth Auther,
+	config []byte,
+	defop EditOperation,
+	errop ErrorOption,
+) error {
+	if !validDefaultOperation(defop) {
+		return newBadOperationError([]string{}, string(defop))
+	}
+	switch errop {
+	case StopOnError, ContinueOnError, RollbackOnError:
+	default:
+		err := mgmterror.NewInvalidValueApplicationError()
+		err.Message = fmt.Sprintf("Invalid error-option: %s", errop)
+		return err
+	}
+
+	doc, err := parseXMLElements(config)
+	if err != nil {
+		mErr := mgmterror.NewMalformedMessageError()
+		mErr.Message = err.Error()
+		return mErr
+	}
+
+	e := &editor{root: n, auth: auth, errop: errop}
+	apply := func() error {
+		for _, cfg := range doc.children {
+			err := e.applyChildren(cfg, n.schema, []string{}, defop)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if errop == RollbackOnError {
+		err = n.withRollback(apply)
+	} else {
+		j := n.journal()
+		j.Begin()
+		err = apply()
+		j.End()
+	}
+	if err != nil {
+		return err
+	}
+	if len(e.errs) > 0 {
+		var errList mgmterror.MgmtErrorList
+		errList.MgmtErrorListAppend(e.errs...)
+		return errList
+	}
+	return nil
+}
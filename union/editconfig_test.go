@@ -0,0 +1,244 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const editConfigSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf-list tags {
+			type string;
+		}
+		list entry {
+			key id;
+			leaf id {
+				type string;
+			}
+			leaf value {
+				type string;
+			}
+		}
+	}`
+
+const (
+	ncOpen  = `<config xmlns:nc="urn:ietf:params:xml:ns:netconf:base:1.0">`
+	topOpen = `<top xmlns="urn:vyatta.com:test:union">`
+)
+
+func newEditConfigTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, editConfigSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	for _, path := range [][]string{
+		{"top", "name", "orig"},
+		{"top", "tags", "a"},
+		{"top", "tags", "b"},
+		{"top", "entry", "one", "value", "1"},
+	} {
+		mustSet(t, root, path...)
+	}
+	return root
+}
+
+func checkEditConfig(
+	t *testing.T,
+	root Node,
+	config string,
+	defop EditOperation,
+	errop ErrorOption,
+	expected string,
+) {
+	t.Helper()
+	if err := root.EditConfig(nil, []byte(config), defop, errop); err != nil {
+		t.Fatalf("Unexpected edit-config error: %s", err)
+	}
+	checkEditConfigResult(t, root, expected)
+}
+
+func checkEditConfigResult(t *testing.T, root Node, expected string) {
+	t.Helper()
+	actual := string(root.ToJSON())
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestEditConfigMerge(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<name>new</name><tags>c</tags>` +
+		`<entry><id>two</id><value>2</value></entry>` +
+		`</top></config>`
+	expected := `{"top":{"entry":[{"id":"one","value":"1"},` +
+		`{"id":"two","value":"2"}],"name":"new","tags":["a","b","c"]}}`
+	checkEditConfig(t, root, config, EditMerge, StopOnError, expected)
+}
+
+func TestEditConfigReplace(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<entry nc:operation="replace"><id>one</id></entry>` +
+		`</top></config>`
+	expected := `{"top":{"entry":[{"id":"one"}],` +
+		`"name":"orig","tags":["a","b"]}}`
+	checkEditConfig(t, root, config, EditMerge, StopOnError, expected)
+}
+
+func TestEditConfigDefaultReplace(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen + `<tags>c</tags></top></config>`
+	expected := `{"top":{"tags":["c"]}}`
+	checkEditConfig(t, root, config, EditReplace, StopOnError, expected)
+}
+
+func TestEditConfigCreateExisting(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<tags nc:operation="create">a</tags></top></config>`
+	err := root.EditConfig(nil, []byte(config), EditMerge, StopOnError)
+	if err == nil {
+		t.Fatal("Create of existing node succeeded")
+	}
+}
+
+func TestEditConfigDeleteMissing(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<tags nc:operation="delete">z</tags></top></config>`
+	err := root.EditConfig(nil, []byte(config), EditMerge, StopOnError)
+	if err == nil {
+		t.Fatal("Delete of missing node succeeded")
+	}
+}
+
+func TestEditConfigRemove(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<tags nc:operation="remove">a</tags>` +
+		`<tags nc:operation="remove">z</tags>` +
+		`<name nc:operation="delete"/>` +
+		`</top></config>`
+	expected := `{"top":{"entry":[{"id":"one","value":"1"}],"tags":["b"]}}`
+	checkEditConfig(t, root, config, EditMerge, StopOnError, expected)
+}
+
+func TestEditConfigNone(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<name>ignored</name>` +
+		`<entry><id>one</id>` +
+		`<value nc:operation="merge">2</value></entry>` +
+		`</top></config>`
+	err := root.EditConfig(nil, []byte(config), EditNone, StopOnError)
+	if err == nil {
+		t.Fatal("Expected data-missing for leaf under operation none")
+	}
+
+	config = ncOpen + topOpen +
+		`<entry><id>one</id>` +
+		`<value nc:operation="merge">2</value></entry>` +
+		`</top></config>`
+	expected := `{"top":{"entry":[{"id":"one","value":"2"}],` +
+		`"name":"orig","tags":["a","b"]}}`
+	checkEditConfig(t, root, config, EditNone, StopOnError, expected)
+}
+
+func TestEditConfigNoneMissingLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"list entry", ncOpen + topOpen +
+			`<entry><id>two</id>` +
+			`<value nc:operation="merge">2</value></entry>` +
+			`</top></config>`},
+		{"container", ncOpen + topOpen +
+			`<name nc:operation="merge">new</name>` +
+			`</top></config>`},
+	}
+	for _, test := range tests {
+		sch := newTestSchema(t, editConfigSchema)
+		root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+		if test.name == "list entry" {
+			mustSet(t, root, "top", "entry", "one", "value", "1")
+		}
+		err := root.EditConfig(nil, []byte(test.config), EditNone, StopOnError)
+		if err == nil {
+			t.Errorf("%s: expected data-missing error", test.name)
+		}
+	}
+}
+
+func TestEditConfigWhitespace(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<tags>
+			c
+		</tags>` +
+		`<entry><id> one </id><value>2</value></entry>` +
+		`</top></config>`
+	expected := `{"top":{"entry":[{"id":"one","value":"2"}],` +
+		`"name":"orig","tags":["a","b","c"]}}`
+	checkEditConfig(t, root, config, EditMerge, StopOnError, expected)
+}
+
+func TestEditConfigNamespace(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<name xmlns="urn:example:other">new</name>` +
+		`</top></config>`
+	if root.EditConfig(nil, []byte(config), EditMerge, StopOnError) == nil {
+		t.Fatal("Element in the wrong namespace accepted")
+	}
+	config = ncOpen + `<top><name>new</name></top></config>`
+	if root.EditConfig(nil, []byte(config), EditMerge, StopOnError) == nil {
+		t.Fatal("Element without a namespace accepted")
+	}
+}
+
+func TestEditConfigErrorOptions(t *testing.T) {
+	config := ncOpen + topOpen +
+		`<tags>c</tags>` +
+		`<tags nc:operation="create">a</tags>` +
+		`<tags>d</tags>` +
+		`</top></config>`
+
+	root := newEditConfigTestTree(t)
+	if root.EditConfig(nil, []byte(config), EditMerge, StopOnError) == nil {
+		t.Fatal("stop-on-error: expected error")
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"}],`+
+		`"name":"orig","tags":["a","b","c"]}}`)
+
+	root = newEditConfigTestTree(t)
+	if root.EditConfig(nil, []byte(config), EditMerge, ContinueOnError) == nil {
+		t.Fatal("continue-on-error: expected error")
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"}],`+
+		`"name":"orig","tags":["a","b","c","d"]}}`)
+
+	root = newEditConfigTestTree(t)
+	if root.EditConfig(nil, []byte(config), EditMerge, RollbackOnError) == nil {
+		t.Fatal("rollback-on-error: expected error")
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"}],`+
+		`"name":"orig","tags":["a","b"]}}`)
+}
+
+func TestEditConfigBadOperation(t *testing.T) {
+	root := newEditConfigTestTree(t)
+	config := ncOpen + topOpen +
+		`<tags nc:operation="frobnicate">c</tags></top></config>`
+	if root.EditConfig(nil, []byte(config), EditMerge, StopOnError) == nil {
+		t.Fatal("Invalid operation accepted")
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+)
+
+// Output filtering. A Filter restricts the encoders to the matching
+// portions of the tree; it is either a NETCONF subtree filter (RFC 6241
+// section 6) or a restricted XPath expression, which is converted to the
+// equivalent subtree filter.
+//
+// Elements are matched on their local name, namespaces are not checked.
+// A content match on a leaf-list selects the whole leaf-list.
+
+// filterElem is a node of a subtree filter
+type filterElem struct {
+	name string
+	//match is set for content match nodes, value is the value to match
+	match    bool
+	value    string
+	children []*filterElem
+}
+
+type Filter struct {
+	elems []*filterElem
+}
+
+// WithFilter limits the output to the parts of the tree the filter
+// selects. An empty filter selects nothing.
+func WithFilter(f *Filter) UnionOption {
+	return func(opts *unionOptions) {
+		if f != nil {
+			opts.filter = &selection{elems: f.elems}
+		}
+	}
+}
+
+func newInvalidFilterError(msg string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Message = msg
+	return err
+}
+
+func newFilterElem(elem *xmlElement) *filterElem {
+	f := &filterElem{name: elem.name.Local}
+	if len(elem.children) == 0 {
+		f.value = strings.TrimSpace(elem.text)
+		f.match = f.value != ""
+		return f
+	}
+	for _, ch := range elem.children {
+		f.children = append(f.children, newFilterElem(ch))
+	}
+	return f
+}
+
+// ParseSubtreeFilter parses a subtree filter, either the contents of a
+// <filter> element or the element itself.
+func ParseSubtreeFilter(input []byte) (*Filter, error) {
+	doc, err := parseXMLElements(input)
+	if err != nil {
+		mErr := mgmterror.NewMalformedMessageError()
+		mErr.Message = err.Error()
+		return nil, mErr
+	}
+	elems := doc.children
+	if len(elems) == 1 && elems[0].name.Local == "filter" &&
+		elems[0].name.Space == netconfBaseNamespace {
+		elems = elems[0].children
+	}
+	f := &Filter{}
+	for _, elem := range elems {
+		f.elems = append(f.elems, newFilterElem(elem))
+	}
+	return f, nil
+}
+
+func localName(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// xpathToken is a token of an XPath expression; kind is the operator
+// character, or xpathName or xpathLiteral.
+type xpathToken struct {
+	kind  byte
+	value string
+}
+
+const (
+	xpathEnd     = 0
+	xpathName    = 'n'
+	xpathLiteral = 'l'
+)
+
+func newUnsupportedXPathError(expr string) error {
+	return newInvalidFilterError(
+		fmt.Sprintf("Unsupported XPath expression: %s", expr))
+}
+
+func isXPathNameChar(c byte) bool {
+	return c == '-' || c == '_' || c == '.' || c == ':' ||
+		(c >= '0' && c <= '9') ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// tokenizeXPath splits expr into the tokens of the supported subset,
+// anything else is rejected.
+func tokenizeXPath(expr string) ([]xpathToken, error) {
+	var toks []xpathToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.IndexByte("/|[]=", c) >= 0:
+			toks = append(toks, xpathToken{kind: c})
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, newInvalidFilterError(
+					fmt.Sprintf("Unterminated literal in XPath: %s", expr))
+			}
+			toks = append(toks, xpathToken{
+				kind:  xpathLiteral,
+				value: expr[i+1 : i+1+end]})
+			i += end + 2
+		case isXPathNameChar(c):
+			start := i
+			for i < len(expr) && isXPathNameChar(expr[i]) {
+				i++
+			}
+			toks = append(toks, xpathToken{
+				kind:  xpathName,
+				value: expr[start:i]})
+		default:
+			return nil, newUnsupportedXPathError(expr)
+		}
+	}
+	return toks, nil
+}
+
+type xpathParser struct {
+	expr string
+	toks []xpathToken
+}
+
+func (p *xpathParser) peek() byte {
+	if len(p.toks) == 0 {
+		return xpathEnd
+	}
+	return p.toks[0].kind
+}
+
+func (p *xpathParser) expect(kind byte) (string, error) {
+	if p.peek() != kind {
+		return "", newUnsupportedXPathError(p.expr)
+	}
+	tok := p.toks[0]
+	p.toks = p.toks[1:]
+	return tok.value, nil
+}
+
+func (p *xpathParser) name() (string, error) {
+	name, err := p.expect(xpathName)
+	if err != nil {
+		return "", err
+	}
+	if name == "." || name == ".." {
+		return "", newUnsupportedXPathError(p.expr)
+	}
+	return localName(name), nil
+}
+
+// predicate parses a [name='value'] predicate
+func (p *xpathParser) predicate() (*filterElem, error) {
+	if _, err := p.expect('['); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect('='); err != nil {
+		return nil, err
+	}
+	value, err := p.expect(xpathLiteral)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(']'); err != nil {
+		return nil, err
+	}
+	return &filterElem{name: name, match: true, value: value}, nil
+}
+
+// path parses an absolute location path
+func (p *xpathParser) path() (*filterElem, error) {
+	if p.peek() != '/' {
+		return nil, newInvalidFilterError(
+			fmt.Sprintf("XPath must be an absolute path: %s", p.expr))
+	}
+	var top, parent *filterElem
+	for p.peek() == '/' {
+		p.expect('/')
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		elem := &filterElem{name: name}
+		for p.peek() == '[' {
+			pred, err := p.predicate()
+			if err != nil {
+				return nil, err
+			}
+			elem.children = append(elem.children, pred)
+		}
+		if parent == nil {
+			top = elem
+		} else {
+			parent.children = append(parent.children, elem)
+		}
+		parent = elem
+	}
+	return top, nil
+}
+
+// ParseXPathFilter parses a restricted XPath select expression: a union
+// ('|') of absolute location paths whose steps may only have
+// [name='value'] predicates.
+func ParseXPathFilter(expr string) (*Filter, error) {
+	toks, err := tokenizeXPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &xpathParser{expr: expr, toks: toks}
+	f := &Filter{}
+	for {
+		top, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		f.elems = append(f.elems, top)
+		switch p.peek() {
+		case xpathEnd:
+			return f, nil
+		case '|':
+			p.expect('|')
+		default:
+			return nil, newUnsupportedXPathError(expr)
+		}
+	}
+}
+
+func (f *filterElem) hasSelection() bool {
+	for _, ch := range f.children {
+		if !ch.match {
+			return true
+		}
+	}
+	return false
+}
+
+// valueMatch checks the values of a leaf or leaf-list, the values must
+// be readable to match.
+func valueMatch(n Node, value string, path []string, opts *unionOptions) bool {
+	if !authorize(opts.auth, path, "read") {
+		return false
+	}
+	if n.GetSchema().ConfigdExt().Secret && opts.shouldHideSecrets(path) {
+		return false
+	}
+	for _, v := range n.SortedChildren() {
+		if v.Name() == value {
+			return true
+		}
+	}
+	return false
+}
+
+// contentMatches checks all of the filter's content match nodes
+func (f *filterElem) contentMatches(
+	n Node,
+	path []string,
+	opts *unionOptions,
+) bool {
+	for _, m := range f.children {
+		if !m.match {
+			continue
+		}
+		if e, ok := n.(*ListEntry); ok && e.Schema.Keys()[0] == m.name {
+			if n.Name() != m.value {
+				return false
+			}
+			continue
+		}
+		ch := n.Child(m.name)
+		if ch == nil ||
+			!valueMatch(ch, m.value, pathutil.CopyAppend(path, m.name), opts) {
+			return false
+		}
+	}
+	return true
+}
+
+// selection is the part of a filter applying to the children of a node,
+// nil selects everything.
+type selection struct {
+	elems []*filterElem
+	//entries is set for the children of a list, which the
+	//list's filter elements all apply to
+	entries bool
+}
+
+// child returns the selection for the children of ch, and whether ch is
+// selected at all.
+func (s *selection) child(
+	ch Node,
+	path []string,
+	opts *unionOptions,
+) (*selection, bool) {
+	var fs []*filterElem
+	for _, f := range s.elems {
+		if s.entries || f.name == ch.Name() {
+			fs = append(fs, f)
+		}
+	}
+	if len(fs) == 0 {
+		return nil, false
+	}
+	if _, ok := ch.(*List); ok {
+		sel := &selection{elems: fs, entries: true}
+		return sel, sel.selectsAny(ch, path, opts)
+	}
+
+	var elems []*filterElem
+	for _, f := range fs {
+		switch {
+		case f.match:
+			if valueMatch(ch, f.value, path, opts) {
+				return nil, true
+			}
+		case len(f.children) == 0:
+			return nil, true
+		case f.contentMatches(ch, path, opts):
+			if !f.hasSelection() {
+				return nil, true
+			}
+			elems = append(elems, f.children...)
+		}
+	}
+	if len(elems) == 0 {
+		return nil, false
+	}
+	sel := &selection{elems: elems}
+	return sel, sel.selectsAny(ch, path, opts)
+}
+
+// selectsAny is true if any readable child of n is selected, so that
+// containment nodes with nothing selected under them are omitted.
+// Selecting the key of a list entry selects the entry.
+func (s *selection) selectsAny(n Node, path []string, opts *unionOptions) bool {
+	if e, ok := n.(*ListEntry); ok {
+		for _, f := range s.elems {
+			if f.name == e.Schema.Keys()[0] {
+				return true
+			}
+		}
+	}
+	for _, ch := range n.SortedChildren() {
+		npath := pathutil.CopyAppend(path, ch.Name())
+		if !authorize(opts.auth, npath, "read") {
+			continue
+		}
+		if _, ok := s.child(ch, npath, opts); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/auth"
+)
+
+func newFilterTestTree(t *testing.T) Node {
+	root := newEditConfigTestTree(t)
+	mustSet(t, root, "top", "entry", "two", "value", "2")
+	return root
+}
+
+func checkFiltered(t *testing.T, root Node, f *Filter, expected string, options ...UnionOption) {
+	t.Helper()
+	options = append(options, WithFilter(f))
+	actual := string(root.ToJSON(options...))
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestSubtreeFilter(t *testing.T) {
+	root := newFilterTestTree(t)
+	for _, test := range []struct {
+		filter   string
+		expected string
+	}{
+		{topOpen + `<name/></top>`, `{"top":{"name":"orig"}}`},
+		{topOpen + `<entry><id>two</id></entry></top>`,
+			`{"top":{"entry":[{"id":"two","value":"2"}]}}`},
+		{topOpen + `<entry><id/></entry></top>`,
+			`{"top":{"entry":[{"id":"one"},{"id":"two"}]}}`},
+		{topOpen + `<entry><value>1</value><id/></entry></top>`,
+			`{"top":{"entry":[{"id":"one","value":"1"}]}}`},
+		{topOpen + `<tags>b</tags><name/></top>`,
+			`{"top":{"name":"orig","tags":["a","b"]}}`},
+		{topOpen + `<entry><id>three</id></entry></top>`, `{}`},
+		{`<filter xmlns="urn:ietf:params:xml:ns:netconf:base:1.0" ` +
+			`type="subtree"/>`, `{}`},
+	} {
+		f, err := ParseSubtreeFilter([]byte(test.filter))
+		if err != nil {
+			t.Fatalf("Unexpected filter error: %s", err)
+		}
+		checkFiltered(t, root, f, test.expected)
+	}
+}
+
+func TestXPathFilter(t *testing.T) {
+	root := newFilterTestTree(t)
+	f, err := ParseXPathFilter(
+		`/utest:top/entry[id='two']/value | /top/name`)
+	if err != nil {
+		t.Fatalf("Unexpected filter error: %s", err)
+	}
+	checkFiltered(t, root, f,
+		`{"top":{"entry":[{"id":"two","value":"2"}],"name":"orig"}}`)
+
+	f, err = ParseXPathFilter(`/top/entry[value="1"]`)
+	if err != nil {
+		t.Fatalf("Unexpected filter error: %s", err)
+	}
+	checkFiltered(t, root, f, `{"top":{"entry":[{"id":"one","value":"1"}]}}`)
+
+	// '|' in a literal is not a union
+	mustSet(t, root, "top", "entry", "three", "value", "1|2")
+	f, err = ParseXPathFilter(`/top/entry[value='1|2']/value|/top/name`)
+	if err != nil {
+		t.Fatalf("Unexpected filter error: %s", err)
+	}
+	checkFiltered(t, root, f,
+		`{"top":{"entry":[{"id":"three","value":"1|2"}],"name":"orig"}}`)
+
+	for _, expr := range []string{
+		`top`,
+		`/top/entry[id]`,
+		`/top/*`,
+		`/top/entry[id='one'`,
+		`/top/entry[id='one]`,
+		`/top//name`,
+		`/top/name |`,
+		`/top/../name`,
+	} {
+		if _, err := ParseXPathFilter(expr); err == nil {
+			t.Errorf("Invalid expression accepted: %s", expr)
+		}
+	}
+}
+
+func TestFilterAuth(t *testing.T) {
+	root := newFilterTestTree(t)
+	auther := newTestAuther(
+		auth.NewTestAuther(
+			auth.NewTestRule(auth.Deny, auth.AllOps, "/top/entry/*/value"),
+			auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+		), true)
+
+	// Values that can't be read can't be matched either
+	f, err := ParseXPathFilter(`/top/entry[value='2']`)
+	if err != nil {
+		t.Fatalf("Unexpected filter error: %s", err)
+	}
+	checkFiltered(t, root, f, `{}`, Authorizer(auther))
+}
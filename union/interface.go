@@ -9,6 +9,8 @@
 package union
 
 import (
+	"io"
+
 	"github.com/danos/config/data"
 	"github.com/danos/config/schema"
 	"github.com/danos/yang/data/datanode"
@@ -54,6 +56,7 @@ type baseNode interface {
 	) ([][]byte, []error)
 	Default() bool
 	Module() string
+	Annotations() map[string]string
 }
 
 //internalSchemaNode describes the internal API for each node type
@@ -92,10 +95,32 @@ type schemaNode interface {
 	ToJSON(options ...UnionOption) []byte
 	ToRFC7951(options ...UnionOption) []byte
 	MarshalInternalJSON(options ...UnionOption) []byte
+	WriteJSON(w io.Writer, options ...UnionOption) error
+	WriteRFC7951(w io.Writer, options ...UnionOption) error
+	WriteInternalJSON(w io.Writer, options ...UnionOption) error
 	ToNETCONF(rootName string, options ...UnionOption) []byte
 	ToXML(rootName string, options ...UnionOption) []byte
+	ToYAML(options ...UnionOption) []byte
+	ToCBOR(options ...UnionOption) []byte
 	Marshal(rootName, encoding string, options ...UnionOption) (string, error)
 	GetHelp(auth Auther, fromSchema bool, path []string) (map[string]string, error)
+	GetCompletion(auth Auther, path []string) ([]*schema.Completion, error)
+	SetAnnotation(auth Auther, path []string, name, value string) error
+	DeleteAnnotation(auth Auther, path []string, name string) error
+	EnableJournal()
+	Undo() error
+	Redo() error
+	Savepoint() data.Savepoint
+	RollbackToSavepoint(sp data.Savepoint) error
+	EditConfig(auth Auther, config []byte, defop EditOperation, errop ErrorOption) error
+	ApplyYangPatch(auth Auther, patch *YangPatch) *YangPatchStatus
+	Insert(auth Auther, path []string, where Position, point string) error
+	Move(auth Auther, path []string, where Position, point string) error
+	Copy(auth Auther, path []string, newKey string) error
+	Rename(auth Auther, path []string, newKey string) error
+	LayerOf(auth Auther, path []string) (string, error)
+	MergeExcludingLayers(names ...string) *data.Node
+	Subscribe(prefix []string, fn ChangeObserver) func()
 }
 
 //Node describes the full external API
@@ -31,12 +31,16 @@ func (b *InternalJSONWriter) EndList(n *List, empty bool, level int) {
 	b.WriteByte('}')
 }
 
+func (n *node) encodeInternalJSON(b *InternalJSONWriter, options ...UnionOption) {
+	b.WriteByte('{')
+	n.Serialize(b, nil, options...)
+	b.WriteByte('}')
+}
+
 func (n *node) MarshalInternalJSON(options ...UnionOption) []byte {
 	var b = InternalJSONWriter{
 		JSONWriter: new(JSONWriter),
 	}
-	b.WriteByte('{')
-	n.Serialize(&b, nil, options...)
-	b.WriteByte('}')
+	n.encodeInternalJSON(&b, options...)
 	return b.Bytes()
 }
@@ -0,0 +1,104 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"github.com/danos/config/data"
+	"github.com/danos/mgmterror"
+)
+
+// The journal records changes to the overlay (candidate) tree so that a
+// session can undo and redo its edits, and a batch of edits can be rolled
+// back to a savepoint without discarding the rest of the session.
+//
+// Each Set, Delete, SetAnnotation and DeleteAnnotation is one change.
+// The journal belongs to the root of the tree; after an Undo, Redo or
+// rollback any Nodes obtained before it are stale and must be looked up
+// again from the root.
+
+func journalError(msg string) error {
+	err := mgmterror.NewOperationFailedApplicationError()
+	err.Message = msg
+	return err
+}
+
+func (n *node) journal() *data.Journal {
+	root := rootNode(n.specialized).getnode()
+	if root.overlay == nil {
+		return nil
+	}
+	return root.overlay.Journal()
+}
+
+// EnableJournal starts journaling changes to the tree. Changes made
+// before it is called can't be undone.
+func (n *node) EnableJournal() {
+	root := rootNode(n.specialized).getnode()
+	if root.overlay == nil || root.overlay.Journal() != nil {
+		return
+	}
+	root.overlay.SetJournal(data.NewJournal())
+}
+
+func (n *node) Undo() error {
+	j := n.journal()
+	if j == nil {
+		return journalError("Undo is not enabled")
+	}
+	if !j.Undo() {
+		return journalError("Nothing to undo")
+	}
+	return nil
+}
+
+func (n *node) Redo() error {
+	j := n.journal()
+	if j == nil {
+		return journalError("Undo is not enabled")
+	}
+	if !j.Redo() {
+		return journalError("Nothing to redo")
+	}
+	return nil
+}
+
+// Savepoint marks the current state of the tree; the journal is enabled
+// if it isn't already.
+func (n *node) Savepoint() data.Savepoint {
+	n.EnableJournal()
+	return n.journal().Savepoint()
+}
+
+// RollbackToSavepoint reverts all changes made since sp was taken. The
+// reverted changes can't be redone.
+func (n *node) RollbackToSavepoint(sp data.Savepoint) error {
+	j := n.journal()
+	if j == nil {
+		return journalError("Undo is not enabled")
+	}
+	if err := j.RollbackTo(sp); err != nil {
+		return journalError("Savepoint is no longer available")
+	}
+	return nil
+}
+
+// withRollback runs fn as a single change, reverting it if fn fails.
+// A journal is used for the duration even if journaling isn't enabled.
+func (n *node) withRollback(fn func() error) error {
+	root := rootNode(n.specialized).getnode()
+	if root.overlay != nil && root.overlay.Journal() == nil {
+		n.EnableJournal()
+		defer root.overlay.SetJournal(nil)
+	}
+	sp := n.Savepoint()
+	j := n.journal()
+	j.Begin()
+	defer j.End()
+	err := fn()
+	if err != nil {
+		n.RollbackToSavepoint(sp)
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const journalSchema = `
+	container top {
+		leaf-list tags {
+			type string;
+		}
+		leaf name {
+			type string;
+			default "none";
+		}
+	}`
+
+func newJournalTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, journalSchema)
+	running := data.New("root")
+	running.SetNoValidate([]string{"top", "tags", "running"})
+	root := NewNode(data.New("root"), running, sch, nil, 0)
+	root.EnableJournal()
+	return root
+}
+
+func mustSet(t *testing.T, root Node, path ...string) {
+	t.Helper()
+	if err := root.Set(nil, path); err != nil {
+		t.Fatalf("Unexpected set error: %s", err)
+	}
+}
+
+func checkTags(t *testing.T, root Node, expected string) {
+	t.Helper()
+	tags, _ := root.Get(nil, []string{"top", "tags"})
+	actual := ""
+	for i, tag := range tags {
+		if i > 0 {
+			actual += " "
+		}
+		actual += tag
+	}
+	if actual != expected {
+		t.Errorf("Unexpected tags, expected %q, got %q", expected, actual)
+	}
+}
+
+func TestUndoSet(t *testing.T) {
+	root := newJournalTestTree(t)
+	mustSet(t, root, "top", "tags", "a")
+	mustSet(t, root, "top", "tags", "b")
+	checkTags(t, root, "a b running")
+
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	checkTags(t, root, "a running")
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	checkTags(t, root, "running")
+	if root.Undo() == nil {
+		t.Error("Undo succeeded with nothing to undo")
+	}
+
+	if err := root.Redo(); err != nil {
+		t.Fatalf("Unexpected redo error: %s", err)
+	}
+	checkTags(t, root, "a running")
+}
+
+func TestUndoDelete(t *testing.T) {
+	root := newJournalTestTree(t)
+	if err := root.Delete(nil, []string{"top", "tags", "running"},
+		DontCheckAuth); err != nil {
+		t.Fatalf("Unexpected delete error: %s", err)
+	}
+	if root.Exists(nil, []string{"top", "tags", "running"}) == nil {
+		t.Fatal("Deleted node still exists")
+	}
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	checkTags(t, root, "running")
+	if root.Child("top").Changed() {
+		t.Error("Tree still changed after undoing delete")
+	}
+}
+
+func TestUndoSetDefault(t *testing.T) {
+	root := newJournalTestTree(t)
+	mustSet(t, root, "top", "name", "set")
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	vals, _ := root.Get(nil, []string{"top", "name"})
+	if len(vals) != 1 || vals[0] != "none" {
+		t.Errorf("Default not restored by undo, got %v", vals)
+	}
+}
+
+func TestRollbackToSavepoint(t *testing.T) {
+	root := newJournalTestTree(t)
+	mustSet(t, root, "top", "tags", "kept")
+	sp := root.Savepoint()
+
+	mustSet(t, root, "top", "tags", "a")
+	root.Delete(nil, []string{"top", "tags", "running"}, DontCheckAuth)
+	checkTags(t, root, "a kept")
+
+	if err := root.RollbackToSavepoint(sp); err != nil {
+		t.Fatalf("Unexpected rollback error: %s", err)
+	}
+	checkTags(t, root, "kept running")
+	if root.Redo() == nil {
+		t.Error("Rolled back change can be redone")
+	}
+}
+
+func TestUndoNotEnabled(t *testing.T) {
+	sch := newTestSchema(t, journalSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "tags", "a")
+	if root.Undo() == nil {
+		t.Error("Undo succeeded without a journal")
+	}
+}
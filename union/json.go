@@ -20,8 +20,16 @@ import (
 
 type JSONWriter struct {
 	bytes.Buffer
-	rfc7951    bool
-	moduleName []string
+	rfc7951     bool
+	annotations bool
+	tagDefaults bool
+	origins     bool
+	moduleName  []string
+	//stream is set when writing to an io.Writer, see WriteJSON
+	stream *jsonStream
+	//member is the name of the last leaf or leaf-list written,
+	//needed to name its metadata member
+	member string
 }
 
 func (b *JSONWriter) pushName(n Node) string {
@@ -62,14 +70,16 @@ func (b *JSONWriter) currentModuleName() string {
 		return ""
 	}
 }
-func (b *JSONWriter) handleModuleName(n Node) {
+func (b *JSONWriter) handleModuleName(n Node) string {
 	if b.rfc7951 {
 		b.pushName(n)
 		if nm := b.currentModuleName(); nm != "" {
 			b.WriteString(nm)
 			b.WriteString(":")
+			return nm + ":"
 		}
 	}
+	return ""
 }
 
 func (b *JSONWriter) writeValue(n Node) {
@@ -122,8 +132,10 @@ func (b *JSONWriter) BeginContainer(n *Container, empty bool, level int) {
 }
 
 func (b *JSONWriter) EndContainer(n *Container, empty bool, level int) {
+	b.writeObjectAnnotations(n)
 	b.WriteByte('}')
 	b.popName()
+	b.flushPoint()
 }
 
 func (b *JSONWriter) BeginList(n *List, empty bool, level int) {
@@ -149,7 +161,9 @@ func (b *JSONWriter) BeginListEntry(n *ListEntry, empty bool, level int, hideSec
 }
 
 func (b *JSONWriter) EndListEntry(n *ListEntry, empty bool, level int) {
+	b.writeObjectAnnotations(n)
 	b.WriteByte('}')
+	b.flushPoint()
 }
 
 func (b *JSONWriter) EndList(n *List, empty bool, level int) {
@@ -159,7 +173,7 @@ func (b *JSONWriter) EndList(n *List, empty bool, level int) {
 
 func (b *JSONWriter) BeginLeaf(n *Leaf, empty bool, level int, hideSecrets bool) {
 	b.WriteByte('"')
-	b.handleModuleName(n)
+	b.member = b.handleModuleName(n) + n.Name()
 	b.WriteString(n.Name())
 	b.WriteString("\":")
 }
@@ -194,7 +208,9 @@ func (b *JSONWriter) WriteLeafValue(
 }
 
 func (b *JSONWriter) EndLeaf(n *Leaf, empty bool, level int) {
+	b.writeLeafAnnotations(n)
 	b.popName()
+	b.flushPoint()
 }
 
 func (b *JSONWriter) BeginLeafList(
@@ -204,7 +220,7 @@ func (b *JSONWriter) BeginLeafList(
 	hideSecrets bool,
 ) {
 	b.WriteByte('"')
-	b.handleModuleName(n)
+	b.member = b.handleModuleName(n) + n.Name()
 	b.WriteString(n.Name())
 	b.WriteString("\":")
 }
@@ -236,7 +252,9 @@ func (b *JSONWriter) WriteLeafListValues(
 }
 
 func (b *JSONWriter) EndLeafList(n *LeafList, empty bool, level int) {
+	b.writeLeafListAnnotations(n)
 	b.popName()
+	b.flushPoint()
 }
 
 func (b *JSONWriter) PrintSep() {
@@ -258,12 +276,28 @@ func (n *node) encodeJSON(b *JSONWriter, options ...UnionOption) []byte {
 	return b.Bytes()
 }
 
+func newJSONWriter(rfc7951 bool, options ...UnionOption) *JSONWriter {
+	var opts unionOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if !rfc7951 {
+		return &JSONWriter{}
+	}
+	return &JSONWriter{
+		rfc7951:     true,
+		annotations: opts.includeAnnotations,
+		tagDefaults: opts.tagDefaults(),
+		origins:     opts.includeOrigin,
+	}
+}
+
 func (n *node) ToJSON(options ...UnionOption) []byte {
-	return n.encodeJSON(&JSONWriter{}, options...)
+	return n.encodeJSON(newJSONWriter(false, options...), options...)
 }
 
 func (n *node) ToRFC7951(options ...UnionOption) []byte {
-	return n.encodeJSON(&JSONWriter{rfc7951: true}, options...)
+	return n.encodeJSON(newJSONWriter(true, options...), options...)
 }
 
 // Take the JSON message and create a UnionTree using the given schema and
@@ -372,10 +406,18 @@ func UnmarshalRFC7951(schemaRoot schema.Node, jsonInput []byte) (Node, error) {
 }
 
 func unmarshalRFC7951IntoNode(ut Node, jsonInput []byte) (err error) {
+	jsonInput, anns, err := stripRFC7951Annotations(ut.GetSchema(), jsonInput)
+	if err != nil {
+		return err
+	}
+
 	datatree, err := encoding.UnmarshalRFC7951(ut.GetSchema(), jsonInput)
 	if err != nil {
 		return err
 	}
 
-	return yangDataIntoTree(ut, datatree)
+	if err := yangDataIntoTree(ut, datatree); err != nil {
+		return err
+	}
+	return applyAnnotations(ut, anns)
 }
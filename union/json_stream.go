@@ -0,0 +1,109 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"io"
+)
+
+// Streaming JSON encoding. The writers still build their output in their
+// buffer, but in streaming mode the buffer is written out at flush points,
+// after a container, list entry, leaf or leaf-list once the buffer has
+// reached the flush size, so only about that much output is held at once
+// and the first bytes are written early.
+
+const DefaultFlushSize = 32 * 1024
+
+type jsonStream struct {
+	w         io.Writer
+	flushSize int
+	//last is the last byte written out
+	last byte
+	//err is the first write error; later output is discarded
+	err error
+}
+
+// FlushSize sets the amount of output buffered between writes by the
+// streaming encoders, eg WriteJSON.
+func FlushSize(size int) UnionOption {
+	return func(opts *unionOptions) {
+		opts.flushSize = size
+	}
+}
+
+func newJSONStream(w io.Writer, options ...UnionOption) *jsonStream {
+	var opts unionOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
+	flushSize := opts.flushSize
+	if flushSize <= 0 {
+		flushSize = DefaultFlushSize
+	}
+	return &jsonStream{w: w, flushSize: flushSize}
+}
+
+// NewStreamingJSONWriter returns a JSONWriter, encoding RFC 7951 if
+// rfc7951 is set, that writes its output to w as it is serialized. Flush
+// must be called once serialization is complete.
+func NewStreamingJSONWriter(w io.Writer, rfc7951 bool) *JSONWriter {
+	return &JSONWriter{
+		rfc7951: rfc7951,
+		stream:  newJSONStream(w),
+	}
+}
+
+// Flush writes out any buffered output of a streaming writer, returning
+// the first error writing the output.
+func (b *JSONWriter) Flush() error {
+	if b.stream == nil {
+		return nil
+	}
+	if b.Len() == 0 {
+		return b.stream.err
+	}
+	b.stream.last = b.Bytes()[b.Len()-1]
+	if b.stream.err == nil {
+		_, b.stream.err = b.stream.w.Write(b.Bytes())
+	}
+	b.Reset()
+	return b.stream.err
+}
+
+func (b *JSONWriter) flushPoint() {
+	if b.stream != nil && b.Len() >= b.stream.flushSize {
+		b.Flush()
+	}
+}
+
+func (n *node) streamJSON(
+	w io.Writer,
+	b *JSONWriter,
+	options ...UnionOption,
+) error {
+	b.stream = newJSONStream(w, options...)
+	n.encodeJSON(b, options...)
+	return b.Flush()
+}
+
+// WriteJSON writes the JSON encoding of the node to w as it is serialized
+func (n *node) WriteJSON(w io.Writer, options ...UnionOption) error {
+	return n.streamJSON(w, newJSONWriter(false, options...), options...)
+}
+
+// WriteRFC7951 writes the RFC 7951 encoding of the node to w as it is
+// serialized
+func (n *node) WriteRFC7951(w io.Writer, options ...UnionOption) error {
+	return n.streamJSON(w, newJSONWriter(true, options...), options...)
+}
+
+// WriteInternalJSON writes the internal JSON encoding of the node to w as
+// it is serialized
+func (n *node) WriteInternalJSON(w io.Writer, options ...UnionOption) error {
+	b := InternalJSONWriter{JSONWriter: new(JSONWriter)}
+	b.stream = newJSONStream(w, options...)
+	n.encodeInternalJSON(&b, options...)
+	return b.Flush()
+}
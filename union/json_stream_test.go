@@ -0,0 +1,76 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// countingWriter records the output and how many writes made it
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func newStreamTestTree(t *testing.T) Node {
+	root := newYangPatchTestTree(t)
+	for i := 0; i < 20; i++ {
+		mustSet(t, root, "top", "entry", fmt.Sprintf("e%d", i),
+			"value", fmt.Sprintf("%d", i))
+	}
+	return root
+}
+
+func TestStreamingEncoders(t *testing.T) {
+	root := newStreamTestTree(t)
+	for _, test := range []struct {
+		name   string
+		write  func(io.Writer, ...UnionOption) error
+		expect []byte
+	}{
+		{"json", root.WriteJSON, root.ToJSON()},
+		{"rfc7951", root.WriteRFC7951, root.ToRFC7951(IncludeOrigin)},
+		{"internal", root.WriteInternalJSON, root.MarshalInternalJSON()},
+	} {
+		var w countingWriter
+		options := []UnionOption{FlushSize(64)}
+		if test.name == "rfc7951" {
+			options = append(options, IncludeOrigin)
+		}
+		if err := test.write(&w, options...); err != nil {
+			t.Fatalf("Unexpected %s write error: %s", test.name, err)
+		}
+		if !bytes.Equal(w.Bytes(), test.expect) {
+			t.Errorf("Unexpected %s output\n   expect=%s\n   actual=%s",
+				test.name, test.expect, w.Bytes())
+		}
+		if w.writes < 2 {
+			t.Errorf("Expected %s output in several writes, got %d",
+				test.name, w.writes)
+		}
+	}
+}
+
+func TestStreamingEncoderWriteError(t *testing.T) {
+	root := newStreamTestTree(t)
+	if err := root.WriteRFC7951(failingWriter{}, FlushSize(64)); err == nil {
+		t.Errorf("Unexpected success writing to a failing writer")
+	}
+}
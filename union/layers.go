@@ -0,0 +1,237 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"sort"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+)
+
+// A layered tree composes its underlay from several read-only layers of
+// configuration, eg factory defaults, platform provided config, system
+// managed config and the user's config. A layer with a higher precedence
+// overrides the leaf values of the lower layers; containers, lists and
+// leaf-lists are merged. The overlay holds the changes made to the
+// composed tree as usual.
+
+const (
+	// OverlayLayer is the layer reported for changes in the overlay
+	OverlayLayer = "overlay"
+	// DefaultLayer is the layer reported for schema defaults
+	DefaultLayer = "default"
+)
+
+type Layer struct {
+	Name       string
+	Precedence int
+	Tree       *data.Node
+	//Origin is the NMDA origin of the layer's config, by
+	//default intended
+	Origin schema.Origin
+}
+
+// sortLayers returns the layers in increasing order of precedence
+func sortLayers(layers []Layer) []Layer {
+	out := make([]Layer, len(layers))
+	copy(out, layers)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Precedence < out[j].Precedence
+	})
+	return out
+}
+
+// mergeLayerData returns a copy of the union of over and under, the
+// values of over's leaves replacing those of under's.
+func mergeLayerData(over, under *data.Node, sch schema.Node) *data.Node {
+	src := over
+	if src == nil {
+		src = under
+	}
+	out := src.Copy()
+	if _, ok := sch.(schema.Leaf); ok && over.NumChildren() > 0 {
+		under = nil
+	}
+
+	//As when setting it, config in one case of a choice evicts the
+	//config in the choice's other cases from the lower layers
+	evicted := make(map[string]bool)
+	if over != nil && under != nil && sch.Choices() != nil {
+		for _, name := range over.ChildNames() {
+			for _, rn := range choiceNodesToRemove(sch.Choices(), name, nil) {
+				evicted[rn] = true
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	for _, parent := range []*data.Node{under, over} {
+		if parent == nil {
+			continue
+		}
+		children := parent.Children()
+		sort.Sort(data.ByUser(children))
+		for _, ch := range children {
+			if _, ok := seen[ch.Name()]; ok {
+				continue
+			}
+			if parent == under && evicted[ch.Name()] {
+				continue
+			}
+			seen[ch.Name()] = struct{}{}
+			chsch := sch.SchemaChild(ch.Name())
+			if chsch == nil {
+				continue
+			}
+			out.AddChild(mergeLayerData(
+				over.Child(ch.Name()), under.Child(ch.Name()), chsch))
+		}
+	}
+	return out
+}
+
+// mergeLayers collapses the layers into a single tree
+func mergeLayers(layers []Layer, sch schema.Node) *data.Node {
+	out := data.New("root")
+	for _, l := range sortLayers(layers) {
+		if l.Tree != nil {
+			out = mergeLayerData(l.Tree, out, sch)
+		}
+	}
+	return out
+}
+
+// NewLayeredRoot returns a tree whose underlay is composed from the
+// layers, the overlay holding the changes made to it.
+func NewLayeredRoot(overlay *data.Node, layers []Layer, sch schema.Tree) *Root {
+	out := NewRoot(overlay, mergeLayers(layers, sch), sch, nil, 0)
+	out.layers = sortLayers(layers)
+	return out
+}
+
+// dataDescendant returns the node at path if it isn't deleted
+func dataDescendant(n *data.Node, path []string) *data.Node {
+	for _, elem := range path {
+		n = n.Child(elem)
+		if n == nil || n.Deleted() {
+			return nil
+		}
+	}
+	return n
+}
+
+// nodePath returns the path from the root to n
+func nodePath(n Node) []string {
+	var path []string
+	for ; n.Parent() != nil; n = n.Parent() {
+		path = append([]string{n.Name()}, path...)
+	}
+	return path
+}
+
+func (n *node) rootLayers() []Layer {
+	if root, ok := rootNode(n.specialized).(*Root); ok {
+		return root.layers
+	}
+	return nil
+}
+
+// LayerOf returns the name of the layer the node at path comes from: the
+// layer with the highest precedence that has it, OverlayLayer if it has
+// been added in the overlay, or DefaultLayer for a schema default. The
+// underlay of a tree without layers is reported as the empty string.
+func (n *node) LayerOf(auth Auther, path []string) (string, error) {
+	if err := n.Exists(auth, path); err != nil {
+		return "", err
+	}
+	return n.layerOf(append(nodePath(n.specialized), path...)), nil
+}
+
+// layerOf returns the layer of the existing node at the absolute path
+func (n *node) layerOf(abs []string) string {
+	root := rootNode(n.specialized).getnode()
+	if over := dataDescendant(root.overlay, abs); over != nil && over.Opaque() {
+		//Replaced in the overlay
+		return OverlayLayer
+	}
+	if dataDescendant(root.underlay, abs) == nil {
+		if dataDescendant(root.overlay, abs) != nil {
+			return OverlayLayer
+		}
+		return DefaultLayer
+	}
+	layers := n.rootLayers()
+	for i := len(layers) - 1; i >= 0; i-- {
+		if dataDescendant(layers[i].Tree, abs) != nil {
+			return layers[i].Name
+		}
+	}
+	return ""
+}
+
+func sameValues(a, b *data.Node) bool {
+	if a.NumChildren() != b.NumChildren() {
+		return false
+	}
+	for _, name := range a.ChildNames() {
+		if b.Child(name) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// pruneLayerData removes from out the config provided unchanged by
+// the lower tree.
+func pruneLayerData(out, lower *data.Node, sch schema.Node) {
+	if _, ok := sch.(schema.Leaf); ok {
+		if sameValues(out, lower) {
+			out.ClearChildren()
+		}
+		return
+	}
+	for _, ch := range out.Children() {
+		lch := lower.Child(ch.Name())
+		if lch == nil {
+			continue
+		}
+		chsch := sch.SchemaChild(ch.Name())
+		if chsch == nil {
+			continue
+		}
+		pruneLayerData(ch, lch, chsch)
+		if ch.NumChildren() == 0 {
+			out.DeleteChild(ch.Name())
+		}
+	}
+}
+
+// MergeExcludingLayers returns the merged tree, without defaults, less
+// the config provided unchanged by the named layers; eg the user's
+// config to be saved, without the platform's config. Config deleted
+// from the named layers can't be represented, and is lost.
+func (n *node) MergeExcludingLayers(names ...string) *data.Node {
+	out := n.MergeWithoutDefaults()
+	exclude := make(map[string]bool)
+	for _, name := range names {
+		exclude[name] = true
+	}
+	var layers []Layer
+	for _, l := range n.rootLayers() {
+		if exclude[l.Name] {
+			layers = append(layers, l)
+		}
+	}
+	if out == nil || len(layers) == 0 {
+		return out
+	}
+	lower := dataDescendant(mergeLayers(layers, rootNode(n.specialized).GetSchema()),
+		nodePath(n.specialized))
+	if lower != nil {
+		pruneLayerData(out, lower, n.schema)
+	}
+	return out
+}
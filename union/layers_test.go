@@ -0,0 +1,146 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+)
+
+const layersSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf mtu {
+			type uint32;
+			default 1500;
+		}
+		leaf-list tags {
+			type string;
+		}
+		list entry {
+			key id;
+			leaf id {
+				type string;
+			}
+			leaf value {
+				type string;
+			}
+		}
+		choice addressing {
+			case static {
+				leaf address {
+					type string;
+				}
+			}
+			case dhcp {
+				leaf client-id {
+					type string;
+				}
+			}
+		}
+	}`
+
+func newLayerData(t *testing.T, sch schema.ModelSet, paths ...[]string) *data.Node {
+	t.Helper()
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	for _, path := range paths {
+		mustSet(t, root, path...)
+	}
+	return root.MergeWithoutDefaults()
+}
+
+func newLayersTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, layersSchema)
+	// Listed out of order to check the precedence is used
+	return NewLayeredRoot(data.New("root"), []Layer{
+		{Name: "user", Precedence: 20, Tree: newLayerData(t, sch,
+			[]string{"top", "entry", "one", "value", "10"})},
+		{Name: "factory", Precedence: 0, Tree: newLayerData(t, sch,
+			[]string{"top", "name", "factory"},
+			[]string{"top", "tags", "a"},
+			[]string{"top", "entry", "one", "value", "1"})},
+		{Name: "platform", Precedence: 10, Tree: newLayerData(t, sch,
+			[]string{"top", "name", "platform"},
+			[]string{"top", "tags", "b"},
+			[]string{"top", "entry", "two", "value", "2"})},
+	}, sch)
+}
+
+func TestLayeredMerge(t *testing.T) {
+	root := newLayersTestTree(t)
+	assertTreeMatchesJson(t, root, `{"top":{"entry":[`+
+		`{"id":"one","value":"10"},{"id":"two","value":"2"}],`+
+		`"mtu":1500,"name":"platform","tags":["a","b"]}}`)
+
+	merged := NewNode(root.Merge(), data.New("root"), root.GetSchema(), nil, 0)
+	assertTreeMatchesJson(t, merged, `{"top":{"entry":[`+
+		`{"id":"one","value":"10"},{"id":"two","value":"2"}],`+
+		`"mtu":1500,"name":"platform","tags":["a","b"]}}`)
+}
+
+func TestLayerOf(t *testing.T) {
+	root := newLayersTestTree(t)
+	mustSet(t, root, "top", "tags", "c")
+	mustSet(t, root, "top", "entry", "two", "value", "20")
+
+	for _, test := range []struct {
+		path  []string
+		layer string
+	}{
+		{[]string{"top", "name", "platform"}, "platform"},
+		{[]string{"top", "tags", "a"}, "factory"},
+		{[]string{"top", "tags", "b"}, "platform"},
+		{[]string{"top", "tags", "c"}, OverlayLayer},
+		{[]string{"top", "entry", "one", "value", "10"}, "user"},
+		{[]string{"top", "entry", "two", "value", "20"}, OverlayLayer},
+		{[]string{"top", "mtu", "1500"}, DefaultLayer},
+	} {
+		layer, err := root.LayerOf(nil, test.path)
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %s", test.path, err)
+			continue
+		}
+		if layer != test.layer {
+			t.Errorf("Unexpected layer for %v\n   expect=%s\n   actual=%s",
+				test.path, test.layer, layer)
+		}
+	}
+
+	// Overridden values no longer exist
+	if _, err := root.LayerOf(nil, []string{"top", "name", "factory"}); err == nil {
+		t.Errorf("Unexpected layer for overridden value")
+	}
+}
+
+func TestMergeExcludingLayers(t *testing.T) {
+	root := newLayersTestTree(t)
+	mustSet(t, root, "top", "tags", "c")
+
+	user := NewNode(root.MergeExcludingLayers("factory", "platform"),
+		data.New("root"), root.GetSchema(), nil, 0)
+	assertTreeMatchesJson(t, user, `{"top":{"entry":[`+
+		`{"id":"one","value":"10"}],"mtu":1500,"tags":["c"]}}`)
+}
+
+func TestLayeredChoice(t *testing.T) {
+	sch := newTestSchema(t, layersSchema)
+	root := NewLayeredRoot(data.New("root"), []Layer{
+		{Name: "factory", Precedence: 0, Tree: newLayerData(t, sch,
+			[]string{"top", "address", "10.0.0.1"})},
+		{Name: "user", Precedence: 10, Tree: newLayerData(t, sch,
+			[]string{"top", "client-id", "cpe"})},
+	}, sch)
+
+	// The higher layer's case replaces the lower layer's
+	assertTreeMatchesJson(t, root,
+		`{"top":{"client-id":"cpe","mtu":1500}}`)
+	if err := root.Exists(nil, []string{"top", "address"}); err == nil {
+		t.Errorf("Lower layer's case not evicted")
+	}
+}
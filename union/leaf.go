@@ -20,7 +20,7 @@ type Leaf struct {
 func (n *Leaf) serialize(b Serializer, path []string, lvl int, opts *unionOptions) {
 	empty := n.Empty()
 	hideSecrets := opts.shouldHideSecrets(path)
-	if !opts.includeDefaults && n.def() {
+	if opts.hideDefault(n) {
 		return
 	}
 	b.BeginLeaf(n, empty, lvl, hideSecrets)
@@ -20,7 +20,7 @@ type LeafList struct {
 func (n *LeafList) serialize(b Serializer, path []string, lvl int, opts *unionOptions) {
 	empty := n.Empty()
 	hideSecrets := opts.shouldHideSecrets(path)
-	if !opts.includeDefaults && n.def() {
+	if opts.hideDefault(n) {
 		return
 	}
 	b.BeginLeafList(n, empty, lvl, hideSecrets)
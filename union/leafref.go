@@ -0,0 +1,214 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/utils/natsort"
+	"github.com/danos/utils/pathutil"
+)
+
+// Value completion from the data: the existing targets of a leafref and
+// the identities of an identityref. Leafref paths are resolved against
+// the union tree, so they include uncommitted changes. Predicates in the
+// path are ignored, which may offer targets the leafref's predicates would
+// exclude; validation still rejects them.
+
+// leafrefSteps resolves the leafref path target of the leaf at path, an
+// absolute data path, into the data path its relative part starts from
+// and the schema steps from there to the target leaf.
+func leafrefSteps(sch schema.Node, path []string, target string) ([]string, []string) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, nil
+	}
+	base := pathutil.Copypath(path)
+	if strings.HasPrefix(target, "/") {
+		base = nil
+		target = target[1:]
+	}
+	elems, ok := splitPathSteps(target)
+	if !ok {
+		return nil, nil
+	}
+	var steps []string
+	for _, elem := range elems {
+		if i := strings.Index(elem, "["); i >= 0 {
+			elem = elem[:i]
+		}
+		switch elem = strings.TrimSpace(elem); elem {
+		case "", ".":
+		case "..":
+			if len(steps) > 0 || len(base) == 0 {
+				return nil, nil
+			}
+			base = base[:len(base)-1]
+			//Leaving a list entry leaves the list too
+			_, isList := schema.Descendant(sch, base).(schema.List)
+			if isList {
+				base = base[:len(base)-1]
+			}
+		default:
+			steps = append(steps, localName(elem))
+		}
+	}
+	return base, steps
+}
+
+// splitPathSteps splits a leafref path on the '/' outside predicates,
+// returning false if its brackets or quotes are unbalanced.
+func splitPathSteps(path string) ([]string, bool) {
+	var steps []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		case c == '/' && depth == 0:
+			steps = append(steps, path[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, false
+	}
+	return append(steps, path[start:]), true
+}
+
+// leafrefTarget returns the schema of the leaf referred to by the leafref
+// t of the leaf at path, an absolute data path, unless it is itself a
+// leafref.
+func leafrefTarget(root schema.Node, path []string, t schema.Leafref) schema.Node {
+	base, steps := leafrefSteps(root, path, t.TargetPath())
+	if len(steps) == 0 {
+		return nil
+	}
+	sn := schema.Descendant(root, base)
+	for _, step := range steps {
+		if _, ok := sn.(schema.List); ok {
+			sn = sn.SchemaChild(step)
+		}
+		if sn == nil {
+			return nil
+		}
+		sn = sn.SchemaChild(step)
+	}
+	if sn == nil {
+		return nil
+	}
+	if _, ok := sn.Type().(schema.Leafref); ok {
+		return nil
+	}
+	return sn
+}
+
+// collectTargets returns the values of the leaf at the schema steps below
+// n, at path, across every list entry on the way.
+func collectTargets(auth Auther, n Node, path, steps []string) []string {
+	if n == nil || !authorize(auth, path, "read") {
+		return nil
+	}
+	if len(steps) == 0 {
+		var out []string
+		for _, v := range n.SortedChildren() {
+			out = append(out, v.Name())
+		}
+		return out
+	}
+	ch := n.Child(steps[0])
+	if ch == nil {
+		return nil
+	}
+	chPath := pathutil.CopyAppend(path, steps[0])
+	list, ok := ch.(*List)
+	if !ok {
+		return collectTargets(auth, ch, chPath, steps[1:])
+	}
+	rest := steps[1:]
+	var out []string
+	for _, entry := range list.SortedChildren() {
+		entryPath := pathutil.CopyAppend(chPath, entry.Name())
+		if len(rest) == 1 && rest[0] == list.Schema.Keys()[0] {
+			if authorize(auth, entryPath, "read") {
+				out = append(out, entry.Name())
+			}
+			continue
+		}
+		out = append(out, collectTargets(auth, entry, entryPath, rest)...)
+	}
+	return out
+}
+
+func sortedUnique(vals []string) []string {
+	sort.Slice(vals, func(i, j int) bool {
+		return natsort.Less(vals[i], vals[j])
+	})
+	out := vals[:0]
+	for i, v := range vals {
+		if i == 0 || v != vals[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// typeCandidates returns the values offered for the type typ of the leaf
+// or leaf-list sch at the absolute data path.
+func (n *node) typeCandidates(
+	auth Auther,
+	sch schema.Node,
+	typ schema.Type,
+	path []string,
+) []string {
+	switch t := typ.(type) {
+	case schema.Leafref:
+		root := rootNode(n.specialized)
+		base, steps := leafrefSteps(root.GetSchema(), path, t.TargetPath())
+		if len(steps) == 0 {
+			return nil
+		}
+		d, err := root.Descendant(nil, base)
+		if err != nil {
+			return nil
+		}
+		return collectTargets(auth, d, base, steps)
+	case schema.Identityref:
+		var out []string
+		for val := range schema.GetTypeInfo(sch, t).Values {
+			out = append(out, val)
+		}
+		return out
+	case schema.Union:
+		var out []string
+		for _, member := range t.Typs() {
+			if mt, ok := member.(schema.Type); ok {
+				out = append(out, n.typeCandidates(auth, sch, mt, path)...)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// valueCandidates returns the values offered for the leaf or leaf-list
+// sch at path, relative to n.
+func (n *node) valueCandidates(auth Auther, sch schema.Node, path []string) []string {
+	abs := append(nodePath(n.specialized), path...)
+	return sortedUnique(
+		n.typeCandidates(auth, sch, sch.Type().(schema.Type), abs))
+}
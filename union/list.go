@@ -18,6 +18,10 @@ type List struct {
 }
 
 func (n *List) serialize(b Serializer, path []string, lvl int, opts *unionOptions) {
+	if opts.page != nil {
+		n.serializePage(b, path, lvl, opts)
+		return
+	}
 	empty := n.Empty()
 	b.BeginList(n, empty, lvl)
 	n.serializeChildren(b, path, lvl, opts)
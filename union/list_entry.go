@@ -18,7 +18,7 @@ type ListEntry struct {
 }
 
 func (n *ListEntry) serialize(b Serializer, path []string, lvl int, opts *unionOptions) {
-	empty := n.serializeIsEmpty(opts.includeDefaults)
+	empty := n.serializeIsEmpty(opts.reportDefaults()) || opts.elided(lvl+1)
 	hideSecrets := opts.shouldHideSecrets(path)
 
 	b.BeginListEntry(n, empty, lvl, hideSecrets)
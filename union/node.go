@@ -404,7 +404,7 @@ func (n *node) traverseUnionChildrenSkip(fn func(Node), skip func(Node) bool) {
 		}
 		return skip != nil && skip(n)
 	}
-	if !n.opaque() {
+	if !n.opaque() && !n.ordered() {
 		//traverse all children in the underlay
 		n.traverseChildren(
 			n.sortDataChildNames(n.underlay.Children()),
@@ -570,6 +570,15 @@ func (n *node) opaque() bool {
 	return n.overlay.Opaque()
 }
 
+// ordered is true when the overlay holds the user order of the
+// children, every child having been copied up to it.
+func (n *node) ordered() bool {
+	if n.overlay == nil {
+		return false
+	}
+	return n.overlay.Ordered()
+}
+
 func (n *node) deleted() bool {
 	if n.overlay == nil {
 		return false
@@ -840,7 +849,11 @@ func (n *node) markDeleted(clearChildFlagsWhenDeletingParent bool) {
 	//copyUp().Data() is the nasty way to
 	//always get an overlay child, probably
 	//should find a cleaner way to do this
+	event, changed := n.deleteEvent()
 	n.copyUp().Data().MarkDeleted(clearChildFlagsWhenDeletingParent)
+	if changed {
+		n.notify(event)
+	}
 }
 
 // Similarly to markDeleted, behaviour is different if we need to check
@@ -1044,11 +1057,13 @@ func (n *node) show(path, curPath []string, opts *unionOptions) (string, error)
 			return yang.NewNodeNotExistsError(append(curPath, hd))
 		},
 		func(last Node) error {
-			var b StringWriter
+			b := StringWriter{tagDefaults: opts.tagDefaults()}
 			//Do we need to pass flags to show or do we treat it as
 			//a shortcut to serialize and make it hide secrets and
 			//defaults.
-			last.serialize(&b, curPath, 0, opts)
+			lastOpts := *opts
+			lastOpts.pageTarget(last)
+			last.serialize(&b, curPath, 0, &lastOpts)
 			out = b.String()
 			return nil
 		},
@@ -1079,6 +1094,7 @@ func (n *node) Serialize(b Serializer, path []string, options ...UnionOption) {
 		path = []string{n.Name()}
 	}
 
+	opts.pageTarget(n.specialized)
 	n.specialized.serialize(b, path, 0, &opts)
 }
 
@@ -1103,7 +1119,7 @@ func (n *node) Set(auth Auther, path []string) error {
 	}
 	err := n.schema.Validate(ctx, []string{}, path)
 	if err != nil {
-		return err
+		return n.suggestPath(auth, err, path, false)
 	}
 
 	//TODO: If is secret, and user isn't secrets group, silently ignrore
@@ -1133,7 +1149,16 @@ func (n *node) Set(auth Auther, path []string) error {
 	if !authorize(auth, path, "update") {
 		return autherr
 	}
-	return callInternalWalker(n.set, path)
+	pending := n.startSet(path)
+	j := n.journal()
+	j.Begin()
+	defer j.End()
+	err = callInternalWalker(n.set, path)
+	if err != nil {
+		return err
+	}
+	n.notifySet(pending)
+	return nil
 }
 
 // Two modes of operation:
@@ -1142,6 +1167,9 @@ func (n *node) Set(auth Auther, path []string) error {
 // - DontCheckAuth: propagate top level authorization.  Top down deletion
 //
 func (n *node) Delete(auth Auther, path []string, checkAuth bool) error {
+	j := n.journal()
+	j.Begin()
+	defer j.End()
 	if checkAuth {
 		if n.Name() != "root" {
 			err := mgmterror.NewOperationFailedApplicationError()
@@ -1157,7 +1185,7 @@ func (n *node) Delete(auth Auther, path []string, checkAuth bool) error {
 	}
 	err := callInternalWalker(n.validateDeletePath, path)
 	if err != nil {
-		return err
+		return n.suggestPath(auth, err, path, true)
 	}
 	if !authorize(auth, path, "delete") {
 		return autherr
@@ -1294,6 +1322,10 @@ func NewNode(overlay, underlay *data.Node, sch schema.Node, parent Node, flags F
 type Root struct {
 	*node
 	Schema schema.Tree
+	//layers composing the underlay, see NewLayeredRoot
+	layers []Layer
+	//observers of changes, see Subscribe
+	observers []*observer
 }
 
 func NewRoot(overlay, underlay *data.Node, sch schema.Tree, parent Node, flags Flags) *Root {
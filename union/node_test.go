@@ -591,3 +591,24 @@ func TestLeafValue(t *testing.T) {
 	//4
 	)
 }
+
+func TestSnapshotUnderlay(t *testing.T) {
+	st, err := getSchema([]byte(baseSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	running := data.NewAtomicNode(nil)
+	running.Publish(data.NewPersistent("root").Set(
+		[]string{"presence", "nondefault", "someval"}))
+
+	root := NewNode(data.New("root"), running.Snapshot(), st, nil, 0)
+	running.Publish(data.NewPersistent("root"))
+	testSequence(
+		genTestExists(t, root, []string{"presence", "nondefault", "someval"}, true),
+		genTestDelete(t, root, []string{"presence"}, true),
+		genTestExists(t, root, []string{"presence"}, false),
+	)
+	if running.Load().Child("presence") != nil {
+		t.Fatal("candidate modified running")
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"github.com/danos/config/schema"
+	"github.com/danos/utils/pathutil"
+)
+
+// Change observers are registered on the root of a union tree and are
+// told about each set, delete and reorder under their path prefix, eg to
+// maintain a live view of pending changes or to autosave the candidate.
+// Observers are called synchronously once the change is complete, so
+// none are told about an edit that fails and is rolled back, and must not
+// modify the tree. Undoing or rolling back a completed change reports the
+// inverse of its events, in reverse order; redoing it reports them again.
+
+type ChangeKind string
+
+const (
+	ChangeSet     ChangeKind = "set"
+	ChangeDelete  ChangeKind = "delete"
+	ChangeReorder ChangeKind = "reorder"
+)
+
+// ChangeEvent describes a change to the tree. Path is the absolute path of
+// the changed node; for a leaf or leaf-list it excludes the value, which
+// is given in Old and New. For a reorder Old and New are the entries of
+// the list or leaf-list before and after.
+type ChangeEvent struct {
+	Kind ChangeKind
+	Path []string
+	Old  []string
+	New  []string
+}
+
+type ChangeObserver func(ChangeEvent)
+
+type observer struct {
+	prefix []string
+	fn     ChangeObserver
+}
+
+// Subscribe registers fn for changes at or below prefix, an absolute path,
+// returning a function that removes the registration.
+func (n *node) Subscribe(prefix []string, fn ChangeObserver) func() {
+	root, ok := rootNode(n.specialized).(*Root)
+	if !ok {
+		return func() {}
+	}
+	obs := &observer{prefix: pathutil.Copypath(prefix), fn: fn}
+	root.observers = append(root.observers, obs)
+	return func() {
+		for i, o := range root.observers {
+			if o == obs {
+				root.observers = append(
+					root.observers[:i:i], root.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (n *node) rootObservers() []*observer {
+	if root, ok := rootNode(n.specialized).(*Root); ok {
+		return root.observers
+	}
+	return nil
+}
+
+func hasPathPrefix(path, prefix []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i, elem := range prefix {
+		if path[i] != elem {
+			return false
+		}
+	}
+	return true
+}
+
+// notify sends event to the observers once the current change is
+// complete, or straight away if there isn't one. If the change is
+// undone or rolled back the inverse event is sent, and if it is redone
+// the event is sent again.
+func (n *node) notify(event ChangeEvent) {
+	root, ok := rootNode(n.specialized).(*Root)
+	if !ok || len(root.observers) == 0 {
+		return
+	}
+	n.journal().Notify(
+		func() { root.deliver(event) },
+		func() { root.deliver(event.inverse()) })
+}
+
+func (r *Root) deliver(event ChangeEvent) {
+	for _, o := range r.observers {
+		if hasPathPrefix(event.Path, o.prefix) {
+			o.fn(event)
+		}
+	}
+}
+
+// inverse is the event reporting that the change described by e has been
+// reverted. Setting values is reverted by a set back to the old values,
+// or by deleting the new ones if there were none.
+func (e ChangeEvent) inverse() ChangeEvent {
+	inv := ChangeEvent{Kind: e.Kind, Path: e.Path, Old: e.New, New: e.Old}
+	switch {
+	case e.Kind == ChangeReorder:
+	case e.Old != nil || e.New != nil:
+		inv.Kind = ChangeSet
+		if len(inv.New) == 0 {
+			inv.Kind, inv.Old, inv.New = ChangeDelete, e.New, nil
+		}
+	case e.Kind == ChangeSet:
+		inv.Kind = ChangeDelete
+	default:
+		inv.Kind = ChangeSet
+	}
+	return inv
+}
+
+// pendingSet is the event for a Set, started before the tree is changed
+type pendingSet struct {
+	event ChangeEvent
+	// values is set when the path ends in the value of a leaf or
+	// leaf-list, whose values are then reported in Old and New
+	values []string
+}
+
+// startSet returns the pending event for setting path, or nil if there
+// are no observers to tell.
+func (n *node) startSet(path []string) *pendingSet {
+	if len(n.rootObservers()) == 0 {
+		return nil
+	}
+	p := &pendingSet{event: ChangeEvent{
+		Kind: ChangeSet,
+		Path: append(nodePath(n.specialized), path...),
+	}}
+	if len(path) == 0 {
+		return p
+	}
+	switch schema.Descendant(n.schema, path[:len(path)-1]).(type) {
+	case schema.Leaf, schema.LeafList:
+		p.values = path[:len(path)-1]
+		p.event.Path = p.event.Path[:len(p.event.Path)-1]
+		p.event.Old, _ = n.get(p.values, make([]string, 0, len(path)))
+	}
+	return p
+}
+
+// notifySet sends the pending event once path has been set. Setting a
+// leaf or leaf-list to the values it already has, eg a leaf to its
+// default, isn't reported.
+func (n *node) notifySet(p *pendingSet) {
+	if p == nil {
+		return
+	}
+	if p.values != nil {
+		p.event.New, _ = n.get(p.values, make([]string, 0, len(p.values)+1))
+		if sameStrings(p.event.Old, p.event.New) {
+			return
+		}
+	}
+	n.notify(p.event)
+}
+
+func sameStrings(a, b []string) bool {
+	return len(a) == len(b) && hasPathPrefix(a, b)
+}
+
+// deleteEvent describes the deletion of n, which must be built before
+// the node is marked deleted. A leaf or leaf-list left empty by deleting
+// its values isn't reported again, so false is returned.
+func (n *node) deleteEvent() (ChangeEvent, bool) {
+	event := ChangeEvent{Kind: ChangeDelete, Path: nodePath(n.specialized)}
+	switch n.specialized.(type) {
+	case *LeafValue:
+		event.Path = event.Path[:len(event.Path)-1]
+		event.Old = []string{n.Name()}
+	case *Leaf, *LeafList:
+		for _, ch := range n.SortedChildren() {
+			event.Old = append(event.Old, ch.Name())
+		}
+		if len(event.Old) == 0 {
+			return event, false
+		}
+	}
+	return event, true
+}
+
+func (n *node) notifyReorder(old, order []string) {
+	if len(n.rootObservers()) == 0 || sameStrings(old, order) {
+		return
+	}
+	n.notify(ChangeEvent{
+		Kind: ChangeReorder,
+		Path: nodePath(n.specialized),
+		Old:  old,
+		New:  order,
+	})
+}
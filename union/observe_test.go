@@ -0,0 +1,152 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const observeSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf mtu {
+			type uint32;
+			default 1500;
+		}
+		leaf-list tags {
+			type string;
+			ordered-by user;
+		}
+		list entry {
+			key id;
+			ordered-by user;
+			leaf id {
+				type string;
+			}
+		}
+	}`
+
+// newObserveTestTree returns a tree and the events sent to an observer
+// of its top container
+func newObserveTestTree(t *testing.T) (Node, *[]ChangeEvent) {
+	sch := newTestSchema(t, observeSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "orig")
+	mustSet(t, root, "top", "tags", "a")
+	mustSet(t, root, "top", "tags", "b")
+	mustSet(t, root, "top", "entry", "one")
+	events := &[]ChangeEvent{}
+	root.Subscribe([]string{"top"}, func(ev ChangeEvent) {
+		*events = append(*events, ev)
+	})
+	return root, events
+}
+
+func checkEvents(t *testing.T, actual, expect []ChangeEvent) {
+	t.Helper()
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Unexpected events\n   expect=%v\n   actual=%v",
+			expect, actual)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	root, events := newObserveTestTree(t)
+	var other []ChangeEvent
+	unsubscribe := root.Subscribe([]string{"top", "entry"},
+		func(ev ChangeEvent) {
+			other = append(other, ev)
+		})
+
+	mustSet(t, root, "top", "name", "new")
+	mustSet(t, root, "top", "tags", "c")
+	mustSet(t, root, "top", "entry", "two")
+	err := root.Move(nil, []string{"top", "entry", "two"}, PositionFirst, "")
+	if err != nil {
+		t.Fatalf("Unexpected move error: %s", err)
+	}
+	if err := root.Delete(nil, []string{"top", "tags", "a"}, false); err != nil {
+		t.Fatalf("Unexpected delete error: %s", err)
+	}
+	if err := root.Delete(nil, []string{"top", "name"}, false); err != nil {
+		t.Fatalf("Unexpected delete error: %s", err)
+	}
+
+	expect := []ChangeEvent{
+		{ChangeSet, []string{"top", "name"}, []string{"orig"}, []string{"new"}},
+		{ChangeSet, []string{"top", "tags"},
+			[]string{"a", "b"}, []string{"a", "b", "c"}},
+		{ChangeSet, []string{"top", "entry", "two"}, nil, nil},
+		{ChangeReorder, []string{"top", "entry"},
+			[]string{"one", "two"}, []string{"two", "one"}},
+		{ChangeDelete, []string{"top", "tags"}, []string{"a"}, nil},
+		{ChangeDelete, []string{"top", "name"}, []string{"new"}, nil},
+	}
+	checkEvents(t, *events, expect)
+	checkEvents(t, other, expect[2:4])
+
+	unsubscribe()
+	mustSet(t, root, "top", "entry", "three")
+	if len(other) != 2 {
+		t.Errorf("Unexpected events after unsubscribe: %v", other[2:])
+	}
+}
+
+func TestSubscribeNoopSet(t *testing.T) {
+	root, events := newObserveTestTree(t)
+	mustSet(t, root, "top", "mtu", "1500")
+	checkEvents(t, *events, nil)
+
+	mustSet(t, root, "top", "mtu", "9000")
+	checkEvents(t, *events, []ChangeEvent{
+		{ChangeSet, []string{"top", "mtu"},
+			[]string{"1500"}, []string{"9000"}},
+	})
+}
+
+func TestSubscribeFailedEdit(t *testing.T) {
+	root, events := newObserveTestTree(t)
+	config := ncOpen + topOpen +
+		`<name>new</name>` +
+		`<tags nc:operation="create">a</tags></top></config>`
+	err := root.EditConfig(nil, []byte(config), EditMerge, StopOnError)
+	if err == nil {
+		t.Fatal("Create of existing node succeeded")
+	}
+	checkEvents(t, *events, nil)
+}
+
+func TestSubscribeUndoRedo(t *testing.T) {
+	root, events := newObserveTestTree(t)
+	root.EnableJournal()
+	mustSet(t, root, "top", "name", "new")
+	mustSet(t, root, "top", "entry", "two")
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	if err := root.Undo(); err != nil {
+		t.Fatalf("Unexpected undo error: %s", err)
+	}
+	if err := root.Redo(); err != nil {
+		t.Fatalf("Unexpected redo error: %s", err)
+	}
+
+	rename := ChangeEvent{ChangeSet, []string{"top", "name"},
+		[]string{"orig"}, []string{"new"}}
+	create := ChangeEvent{ChangeSet, []string{"top", "entry", "two"}, nil, nil}
+	checkEvents(t, *events, []ChangeEvent{
+		rename,
+		create,
+		{ChangeDelete, []string{"top", "entry", "two"}, nil, nil},
+		{ChangeSet, []string{"top", "name"},
+			[]string{"new"}, []string{"orig"}},
+		rename,
+	})
+}
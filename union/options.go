@@ -7,9 +7,22 @@ package union
 
 type unionOptions struct {
 	auth             Auther
-	includeDefaults  bool
+	withDefaults     WithDefaultsMode
 	hideSecrets      bool
 	forceShowSecrets bool
+	//Only the RFC 7951 and XML encodings carry annotations
+	includeAnnotations bool
+	includeOrigin      bool
+	//flushSize only applies to the streaming encoders
+	flushSize int
+	//sids only applies to the CBOR encoding
+	sids *SIDMap
+	//filter is the part of the filter applying to the children
+	//of the node being serialized
+	filter *selection
+	depth  int
+	//page only applies to the list being serialized
+	page *ListPage
 }
 
 type UnionOption func(*unionOptions)
@@ -20,14 +33,21 @@ func Authorizer(auth Auther) UnionOption {
 	}
 }
 
+// IncludeDefaults is the report-all with-defaults mode
 func IncludeDefaults(opts *unionOptions) {
-	opts.includeDefaults = true
+	opts.withDefaults = WithDefaultsReportAll
 }
 
 func HideSecrets(opts *unionOptions) {
 	opts.hideSecrets = true
 }
 
+// IncludeAnnotations adds any RFC 7952 metadata annotations on the
+// nodes to the RFC 7951 and XML encodings.
+func IncludeAnnotations(opts *unionOptions) {
+	opts.includeAnnotations = true
+}
+
 // ForceShowSecrets forces secrets to not be filtered, even if the usual secret
 // filtering logic would suggest they should be filtered.
 func ForceShowSecrets(opts *unionOptions) {
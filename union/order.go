@@ -0,0 +1,176 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"fmt"
+
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// Position is where an entry of an ordered-by user list or leaf-list is
+// placed, relative to the other entries or to a given entry.
+type Position string
+
+const (
+	PositionFirst  Position = "first"
+	PositionLast   Position = "last"
+	PositionBefore Position = "before"
+	PositionAfter  Position = "after"
+)
+
+func newNotUserOrderedError(path []string) error {
+	err := mgmterror.NewOperationFailedApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = "Not an ordered-by user list or leaf-list"
+	return err
+}
+
+func newBadPositionError(path []string, where Position) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = fmt.Sprintf("Invalid position: %s", where)
+	return err
+}
+
+// reorderChildren sets the user order of the children of a list or
+// leaf-list. The underlay order can't be changed, so every entry is
+// copied up and the overlay marked as holding the order; the entries'
+// own contents still come from the underlay.
+func (n *node) reorderChildren(names []string) {
+	for _, name := range names {
+		if ch := n.Child(name); ch != nil {
+			ch.copyUp()
+		}
+	}
+	over := n.copyUp().Data()
+	over.MarkOrdered()
+	for _, name := range names {
+		//Re-adding assigns the next index
+		over.AddChild(over.Child(name))
+	}
+}
+
+// positionChild moves the existing child name to the given position,
+// point being the child it is placed before or after.
+func (n *node) positionChild(
+	path []string,
+	name string,
+	where Position,
+	point string,
+) error {
+	if n.schema.OrdBy() != "user" {
+		return newNotUserOrderedError(path)
+	}
+	names := make([]string, 0, n.NumChildren())
+	var old []string
+	for _, ch := range n.SortedChildren() {
+		old = append(old, ch.Name())
+		if ch.Name() != name {
+			names = append(names, ch.Name())
+		}
+	}
+
+	pos := len(names)
+	switch where {
+	case PositionFirst:
+		pos = 0
+	case PositionLast:
+	case PositionBefore, PositionAfter:
+		pos = -1
+		for i, nm := range names {
+			if nm == point {
+				pos = i
+				break
+			}
+		}
+		if pos < 0 {
+			return yang.NewNodeNotExistsError(pathutil.CopyAppend(path, point))
+		}
+		if where == PositionAfter {
+			pos++
+		}
+	default:
+		return newBadPositionError(path, where)
+	}
+
+	order := make([]string, 0, len(names)+1)
+	order = append(order, names[:pos]...)
+	order = append(order, name)
+	order = append(order, names[pos:]...)
+	n.reorderChildren(order)
+	n.notifyReorder(old, order)
+	return nil
+}
+
+// positionEntry moves the existing list entry or leaf-list value at path
+func (n *node) positionEntry(
+	auth Auther,
+	path []string,
+	where Position,
+	point string,
+) error {
+	parentPath := path[:len(path)-1]
+	parent, err := n.Descendant(auth, parentPath)
+	if err != nil {
+		return err
+	}
+	return parent.getnode().positionChild(
+		parentPath, path[len(path)-1], where, point)
+}
+
+// Insert creates the list entry or leaf-list value at path, which must not
+// already exist, at the given position in its ordered-by user list or
+// leaf-list. Point is the key or value of the sibling it is placed before
+// or after.
+func (n *node) Insert(
+	auth Auther,
+	path []string,
+	where Position,
+	point string,
+) error {
+	if len(path) < 2 {
+		return newNotUserOrderedError(path)
+	}
+	sch := schema.Descendant(n.schema, path[:len(path)-1])
+	if sch != nil && sch.OrdBy() != "user" {
+		return newNotUserOrderedError(path[:len(path)-1])
+	}
+	if n.configExists(path) {
+		return newDataExistsError(path)
+	}
+	return n.withRollback(func() error {
+		if err := n.Set(auth, path); err != nil {
+			return err
+		}
+		return n.positionEntry(auth, path, where, point)
+	})
+}
+
+// Move places the existing list entry or leaf-list value at path at the
+// given position in its ordered-by user list or leaf-list.
+func (n *node) Move(
+	auth Auther,
+	path []string,
+	where Position,
+	point string,
+) error {
+	if len(path) < 2 {
+		return newNotUserOrderedError(path)
+	}
+	if !n.configExists(path) {
+		return newDataMissingError(path)
+	}
+	if !authorize(auth, path, "update") {
+		return autherr
+	}
+	j := n.journal()
+	j.Begin()
+	defer j.End()
+	return n.positionEntry(auth, path, where, point)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+
+	"github.com/danos/config/auth"
+	"github.com/danos/config/data"
+)
+
+func TestInsert(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	for _, test := range []struct {
+		path  []string
+		where Position
+		point string
+	}{
+		{[]string{"top", "entry", "zero"}, PositionFirst, ""},
+		{[]string{"top", "entry", "two"}, PositionLast, ""},
+		{[]string{"top", "entry", "half"}, PositionAfter, "zero"},
+		{[]string{"top", "tags", "c"}, PositionBefore, "b"},
+	} {
+		if err := root.Insert(nil, test.path, test.where, test.point); err != nil {
+			t.Fatalf("Unexpected insert error for %v: %s", test.path, err)
+		}
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"zero"},`+
+		`{"id":"half"},{"id":"one","value":"1"},{"id":"two"}],`+
+		`"name":"orig","tags":["a","c","b"]}}`)
+}
+
+func TestInsertErrors(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	for _, test := range []struct {
+		path  []string
+		where Position
+		point string
+	}{
+		{[]string{"top", "entry", "one"}, PositionFirst, ""},
+		{[]string{"top", "entry", "two"}, PositionBefore, "three"},
+		{[]string{"top", "entry", "two"}, Position("middle"), ""},
+		{[]string{"top", "name", "new"}, PositionFirst, ""},
+	} {
+		if err := root.Insert(nil, test.path, test.where, test.point); err == nil {
+			t.Errorf("Unexpected insert success for %v", test.path)
+		}
+	}
+	// Failed inserts leave the tree unchanged
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"}],`+
+		`"name":"orig","tags":["a","b"]}}`)
+
+	auther := newTestAuther(
+		auth.NewTestAuther(
+			auth.NewTestRule(auth.Deny, auth.AllOps, "/top/tags"),
+			auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+		), true)
+	err := root.Insert(auther, []string{"top", "tags", "c"}, PositionFirst, "")
+	if err == nil {
+		t.Errorf("Unexpected insert success without authorization")
+	}
+}
+
+func TestMove(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	mustSet(t, root, "top", "entry", "two", "value", "2")
+	mustSet(t, root, "top", "entry", "three", "value", "3")
+
+	err := root.Move(nil, []string{"top", "entry", "three"}, PositionBefore, "one")
+	if err != nil {
+		t.Fatalf("Unexpected move error: %s", err)
+	}
+	err = root.Move(nil, []string{"top", "tags", "a"}, PositionAfter, "b")
+	if err != nil {
+		t.Fatalf("Unexpected move error: %s", err)
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"three","value":"3"},`+
+		`{"id":"one","value":"1"},{"id":"two","value":"2"}],`+
+		`"name":"orig","tags":["b","a"]}}`)
+
+	for _, path := range [][]string{
+		{"top", "entry", "four"},
+		{"top", "tags", "c"},
+	} {
+		if err := root.Move(nil, path, PositionFirst, ""); err == nil {
+			t.Errorf("Unexpected move success for %v", path)
+		}
+	}
+	err = root.Move(nil, []string{"top", "entry", "one"}, PositionAfter, "one")
+	if err == nil {
+		t.Errorf("Unexpected move success relative to itself")
+	}
+}
+
+func TestMoveUnderlay(t *testing.T) {
+	running := newYangPatchTestTree(t)
+	mustSet(t, running, "top", "entry", "two", "value", "2")
+	root := NewNode(data.New("root"), running.Merge(), running.GetSchema(),
+		nil, 0)
+
+	err := root.Move(nil, []string{"top", "entry", "two"}, PositionFirst, "")
+	if err != nil {
+		t.Fatalf("Unexpected move error: %s", err)
+	}
+	mustSet(t, root, "top", "entry", "three")
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"two","value":"2"},`+
+		`{"id":"one","value":"1"},{"id":"three"}],`+
+		`"name":"orig","tags":["a","b"]}}`)
+
+	// The entries are reordered, not replaced
+	list := root.Child("top").Child("entry").getnode()
+	if list.opaque() {
+		t.Errorf("Reordered list is opaque")
+	}
+}
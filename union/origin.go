@@ -0,0 +1,96 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"github.com/danos/config/schema"
+)
+
+// NMDA (RFC 8342) origin reporting. With IncludeOrigin the RFC 7951 and
+// XML encodings annotate the nodes with their origin: schema defaults are
+// default, config from a layer has the layer's origin and all other
+// config is intended. As the origin is inherited, only top level nodes
+// and nodes whose origin differs from their parent's are annotated.
+//
+// The origin of state merged with the config is added by
+// schema.ComponentGetStateWithOrigin.
+
+// IncludeOrigin adds the ietf-origin:origin annotation to the RFC 7951
+// and XML encodings.
+func IncludeOrigin(opts *unionOptions) {
+	opts.includeOrigin = true
+}
+
+func (n *node) origin() schema.Origin {
+	if n.def() {
+		return schema.OriginDefault
+	}
+	layers := n.rootLayers()
+	if len(layers) == 0 {
+		return schema.OriginIntended
+	}
+	name := n.layerOf(nodePath(n.specialized))
+	for _, l := range layers {
+		if l.Name == name && l.Origin != "" {
+			return l.Origin
+		}
+	}
+	return schema.OriginIntended
+}
+
+// originParent returns the nearest ancestor carrying annotations, those
+// of lists and leaf-lists being on their entries.
+func originParent(n Node) Node {
+	p := n.Parent()
+	switch p.(type) {
+	case *List, *LeafList:
+		p = p.Parent()
+	}
+	return p
+}
+
+func needsOrigin(n Node) bool {
+	p := originParent(n)
+	if p == nil {
+		return true
+	}
+	if _, ok := p.(*Root); ok {
+		return true
+	}
+	return p.getnode().origin() != n.getnode().origin()
+}
+
+// originAnnotations returns anns with n's origin added if required
+func originAnnotations(n Node, anns map[string]string) map[string]string {
+	if !needsOrigin(n) {
+		return anns
+	}
+	return withAnnotation(anns, schema.OriginAnnotation,
+		string(n.getnode().origin()))
+}
+
+// nodeAnnotations returns the metadata to be written for n
+func (b *JSONWriter) nodeAnnotations(n Node) map[string]string {
+	var anns map[string]string
+	if b.writeAnnotations() {
+		anns = n.Annotations()
+	}
+	if b.rfc7951 && b.origins {
+		anns = originAnnotations(n, anns)
+	}
+	return anns
+}
+
+// nodeAnnotations returns the metadata to be written for n
+func (enc *XMLWriter) nodeAnnotations(n Node) map[string]string {
+	var anns map[string]string
+	if enc.annotations {
+		anns = n.Annotations()
+	}
+	if enc.origins {
+		anns = originAnnotations(n, anns)
+	}
+	return anns
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+)
+
+const (
+	intendedOrigin = `{"ietf-origin:origin":"ietf-origin:intended"}`
+	systemOrigin   = `{"ietf-origin:origin":"ietf-origin:system"}`
+)
+
+func TestOriginDefaults(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	expected := `{"test-union:top":{"desc":"d",` +
+		`"mtu":1500,"@mtu":{"ietf-origin:origin":"ietf-origin:default"},` +
+		`"name":"x","@":` + intendedOrigin + `}}`
+	actual := string(root.ToRFC7951(IncludeOrigin,
+		WithDefaults(WithDefaultsReportAll)))
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+
+	// Origin isn't part of the internal JSON encoding
+	expected = `{"top":{"desc":"d","name":"x"}}`
+	if actual := string(root.ToJSON(IncludeOrigin)); actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestOriginXML(t *testing.T) {
+	root := newWithDefaultsTestTree(t)
+	out := string(root.ToXML("data", IncludeOrigin,
+		WithDefaults(WithDefaultsReportAll)))
+	for _, expected := range []string{
+		`xmlns:ietf-origin="urn:ietf:params:xml:ns:yang:ietf-origin"`,
+		`:origin="ietf-origin:intended"`,
+		`:origin="ietf-origin:default"`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Missing %s in\n%s", expected, out)
+		}
+	}
+}
+
+func TestOriginLayers(t *testing.T) {
+	sch := newTestSchema(t, layersSchema)
+	root := NewLayeredRoot(data.New("root"), []Layer{
+		{Name: "factory", Tree: newLayerData(t, sch,
+			[]string{"top", "tags", "a"},
+			[]string{"top", "entry", "one", "value", "1"})},
+		{Name: "platform", Precedence: 10, Origin: schema.OriginSystem,
+			Tree: newLayerData(t, sch,
+				[]string{"top", "name", "platform"},
+				[]string{"top", "tags", "b"},
+				[]string{"top", "entry", "two", "value", "2"})},
+	}, sch)
+
+	// The container is in both layers, so comes from the platform
+	expected := `{"test-union:top":{"entry":[` +
+		`{"id":"one","value":"1","@":` + intendedOrigin + `},` +
+		`{"id":"two","value":"2"}],"name":"platform",` +
+		`"tags":["a","b"],"@tags":[` + intendedOrigin + `,null],` +
+		`"@":` + systemOrigin + `}}`
+	actual := string(root.ToRFC7951(IncludeOrigin))
+	if actual != expected {
+		t.Errorf("Unexpected result\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"sort"
+
+	"github.com/danos/utils/natsort"
+	"github.com/danos/utils/pathutil"
+)
+
+// List pagination, along the lines of the IETF list-pagination draft.
+// A ListPage applies to the list being serialized, eg by calling ToJSON
+// on a List, or Show with a path ending in the list. Only the entries in
+// the window are built from the union children unless the page sorts on,
+// or filters by, leaf values.
+
+type ListPage struct {
+	//Where selects entries whose leaves have the given values
+	Where map[string]string
+	//SortBy is the leaf to order the entries by, the list's key
+	//giving system order. By default the list's own order is used.
+	SortBy     string
+	Descending bool
+	//Cursor starts the window after the entry with this key,
+	//before Offset is applied
+	Cursor string
+	Offset int
+	//Limit is the maximum number of entries, 0 is unlimited
+	Limit int
+
+	//Total is set to the number of entries matching Where
+	Total int
+}
+
+// Paginate serializes a window of the list's entries. The page's Total
+// is set during serialization.
+func Paginate(page *ListPage) UnionOption {
+	return func(opts *unionOptions) {
+		opts.page = page
+	}
+}
+
+// pageTarget drops the page unless n is a list
+func (opts *unionOptions) pageTarget(n Node) {
+	if _, ok := n.(*List); !ok {
+		opts.page = nil
+	}
+}
+
+// orderedChildNames returns the names of the children in list order
+// without building the union children.
+func (n *node) orderedChildNames() []string {
+	seen := make(map[string]struct{})
+	var names []string
+	add := func(candidates []string) {
+		for _, name := range candidates {
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			if over := n.overlay.Child(name); over != nil && over.Deleted() {
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	if !n.opaque() {
+		add(n.sortDataChildNames(n.underlay.Children()))
+	}
+	add(n.sortDataChildNames(n.overlay.Children()))
+	if n.schema.OrdBy() != "user" {
+		sort.Slice(names, func(i, j int) bool {
+			return natsort.Less(names[i], names[j])
+		})
+	}
+	return names
+}
+
+type pageEntry struct {
+	name  string
+	entry Node
+	//value is the value of the SortBy leaf
+	value string
+}
+
+func leafValue(n Node, name string) (string, bool) {
+	leaf := n.Child(name)
+	if leaf == nil {
+		return "", false
+	}
+	vals := leaf.SortedChildren()
+	if len(vals) == 0 {
+		return "", false
+	}
+	return vals[0].Name(), true
+}
+
+// pageEntries returns the readable entries matching the page's Where,
+// in the page's order.
+func (n *List) pageEntries(path []string, opts *unionOptions) []pageEntry {
+	page := opts.page
+	byLeaf := page.SortBy != "" && page.SortBy != n.Schema.Keys()[0]
+	var entries []pageEntry
+	for _, name := range n.orderedChildNames() {
+		epath := pathutil.CopyAppend(path, name)
+		if !authorize(opts.auth, epath, "read") {
+			continue
+		}
+		e := pageEntry{name: name}
+		if len(page.Where) > 0 || byLeaf {
+			e.entry = n.Child(name)
+			if e.entry == nil {
+				continue
+			}
+		}
+		matched := true
+		for leaf, value := range page.Where {
+			ch := e.entry.Child(leaf)
+			if ch == nil || !valueMatch(ch, value,
+				pathutil.CopyAppend(epath, leaf), opts) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if byLeaf {
+			e.value, _ = leafValue(e.entry, page.SortBy)
+		}
+		entries = append(entries, e)
+	}
+
+	switch {
+	case byLeaf:
+		sort.SliceStable(entries, func(i, j int) bool {
+			return natsort.Less(entries[i].value, entries[j].value)
+		})
+	case page.SortBy != "":
+		sort.SliceStable(entries, func(i, j int) bool {
+			return natsort.Less(entries[i].name, entries[j].name)
+		})
+	}
+	if page.Descending {
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	}
+	return entries
+}
+
+func (n *List) serializePage(b Serializer, path []string, lvl int, opts *unionOptions) {
+	page := opts.page
+	entries := n.pageEntries(path, opts)
+	page.Total = len(entries)
+
+	if page.Cursor != "" {
+		start := len(entries)
+		for i, e := range entries {
+			if e.name == page.Cursor {
+				start = i + 1
+				break
+			}
+		}
+		entries = entries[start:]
+	}
+	if page.Offset > len(entries) {
+		entries = nil
+	} else if page.Offset > 0 {
+		entries = entries[page.Offset:]
+	}
+	if page.Limit > 0 && page.Limit < len(entries) {
+		entries = entries[:page.Limit]
+	}
+
+	window := make([]Node, 0, len(entries))
+	for _, e := range entries {
+		if e.entry == nil {
+			e.entry = n.Child(e.name)
+		}
+		if e.entry != nil {
+			window = append(window, e.entry)
+		}
+	}
+
+	entryOpts := *opts
+	entryOpts.page = nil
+	empty := len(window) == 0
+	b.BeginList(n, empty, lvl)
+	n.serializeNodes(b, window, path, lvl, &entryOpts)
+	b.EndList(n, empty, lvl)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+)
+
+func newPaginateTestTree(t *testing.T) Node {
+	root := newFilterTestTree(t)
+	mustSet(t, root, "top", "entry", "three", "value", "0")
+	mustSet(t, root, "top", "entry", "four", "value", "2")
+	return root
+}
+
+func TestPaginate(t *testing.T) {
+	root := newPaginateTestTree(t)
+	list, err := root.Descendant(nil, []string{"top", "entry"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, test := range []struct {
+		page     ListPage
+		total    int
+		expected string
+	}{
+		{ListPage{Limit: 2}, 4,
+			`{"entry":[{"id":"four","value":"2"},{"id":"one","value":"1"}]}`},
+		{ListPage{Offset: 1, Limit: 2}, 4,
+			`{"entry":[{"id":"one","value":"1"},{"id":"three","value":"0"}]}`},
+		{ListPage{Cursor: "one", Limit: 1}, 4,
+			`{"entry":[{"id":"three","value":"0"}]}`},
+		{ListPage{SortBy: "value", Limit: 2}, 4,
+			`{"entry":[{"id":"three","value":"0"},{"id":"one","value":"1"}]}`},
+		{ListPage{SortBy: "id", Descending: true, Limit: 2}, 4,
+			`{"entry":[{"id":"two","value":"2"},{"id":"three","value":"0"}]}`},
+		{ListPage{Where: map[string]string{"value": "2"}}, 2,
+			`{"entry":[{"id":"four","value":"2"},{"id":"two","value":"2"}]}`},
+		{ListPage{Offset: 10}, 4, `{"entry":[]}`},
+	} {
+		page := test.page
+		actual := string(list.ToJSON(Paginate(&page)))
+		if actual != test.expected {
+			t.Errorf("Unexpected result for %+v\n   expect=%s\n   actual=%s",
+				test.page, test.expected, actual)
+		}
+		if page.Total != test.total {
+			t.Errorf("Unexpected total for %+v: %d", test.page, page.Total)
+		}
+	}
+}
+
+func TestPaginateShow(t *testing.T) {
+	root := newPaginateTestTree(t)
+	out, err := root.Show([]string{"top", "entry"},
+		Paginate(&ListPage{Limit: 1}))
+	if err != nil {
+		t.Fatalf("Unexpected show error: %s", err)
+	}
+	if !strings.Contains(out, "entry four") ||
+		strings.Contains(out, "entry one") {
+		t.Errorf("Unexpected show output: %s", out)
+	}
+
+	// The page only applies when the list is being serialized
+	out, err = root.Show([]string{"top"}, Paginate(&ListPage{Limit: 1}))
+	if err != nil {
+		t.Fatalf("Unexpected show error: %s", err)
+	}
+	if !strings.Contains(out, "entry one") {
+		t.Errorf("Unexpected show output: %s", out)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RESTCONF (RFC 8040 section 4.8) query parameters for the encoders.
+//
+// The depth parameter counts the node being serialized as level 1, a list
+// and its entries being a single level; nodes below the limit are elided.
+// The fields parameter is converted to a Filter applying to the children
+// of the node being serialized, and used with WithFilter.
+
+// Depth limits the number of levels of the output, 0 being unbounded.
+func Depth(depth int) UnionOption {
+	return func(opts *unionOptions) {
+		opts.depth = depth
+	}
+}
+
+// elided is true if the children at lvl are below the depth limit
+func (opts *unionOptions) elided(lvl int) bool {
+	return opts.depth > 0 && lvl >= opts.depth
+}
+
+type fieldsParser struct {
+	expr string
+	pos  int
+}
+
+func (p *fieldsParser) error(msg string) error {
+	return newInvalidFilterError(
+		fmt.Sprintf("Invalid fields %q at %d: %s", p.expr, p.pos, msg))
+}
+
+func (p *fieldsParser) peek() byte {
+	if p.pos >= len(p.expr) {
+		return 0
+	}
+	return p.expr[p.pos]
+}
+
+func (p *fieldsParser) identifier() (string, error) {
+	start := p.pos
+	for p.pos < len(p.expr) && !strings.ContainsRune("/;()", rune(p.expr[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.error("missing node name")
+	}
+	return localName(p.expr[start:p.pos]), nil
+}
+
+// path parses a '/' separated path returning its first and last elements
+func (p *fieldsParser) path() (*filterElem, *filterElem, error) {
+	var first, last *filterElem
+	for {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, nil, err
+		}
+		elem := &filterElem{name: name}
+		if first == nil {
+			first = elem
+		} else {
+			last.children = append(last.children, elem)
+		}
+		last = elem
+		if p.peek() != '/' {
+			return first, last, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *fieldsParser) list() ([]*filterElem, error) {
+	var elems []*filterElem
+	for {
+		first, last, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == '(' {
+			p.pos++
+			if last.children, err = p.list(); err != nil {
+				return nil, err
+			}
+			if p.peek() != ')' {
+				return nil, p.error("missing ')'")
+			}
+			p.pos++
+		}
+		elems = append(elems, first)
+		if p.peek() != ';' {
+			return elems, nil
+		}
+		p.pos++
+	}
+}
+
+// ParseFields parses the value of a RESTCONF fields query parameter, eg
+// "name;entry(id;value)".
+func ParseFields(expr string) (*Filter, error) {
+	p := &fieldsParser{expr: expr}
+	elems, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(expr) {
+		return nil, p.error("unexpected character")
+	}
+	return &Filter{elems: elems}, nil
+}
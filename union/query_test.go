@@ -0,0 +1,58 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"testing"
+)
+
+func TestDepth(t *testing.T) {
+	root := newFilterTestTree(t)
+	for _, test := range []struct {
+		depth    int
+		expected string
+	}{
+		{1, `{"top":{}}`},
+		{2, `{"top":{"entry":[{"id":"one"},{"id":"two"}],` +
+			`"name":"orig","tags":["a","b"]}}`},
+		{3, `{"top":{"entry":[{"id":"one","value":"1"},` +
+			`{"id":"two","value":"2"}],"name":"orig","tags":["a","b"]}}`},
+		{0, `{"top":{"entry":[{"id":"one","value":"1"},` +
+			`{"id":"two","value":"2"}],"name":"orig","tags":["a","b"]}}`},
+	} {
+		actual := string(root.ToJSON(Depth(test.depth)))
+		if actual != test.expected {
+			t.Errorf("Unexpected result for depth %d\n   expect=%s\n   actual=%s",
+				test.depth, test.expected, actual)
+		}
+	}
+}
+
+func TestFields(t *testing.T) {
+	root := newFilterTestTree(t)
+	f, err := ParseFields("utest:top(name;entry/value)")
+	if err != nil {
+		t.Fatalf("Unexpected fields error: %s", err)
+	}
+	checkFiltered(t, root, f, `{"top":{"entry":[{"id":"one","value":"1"},`+
+		`{"id":"two","value":"2"}],"name":"orig"}}`)
+
+	// Fields are relative to the node being serialized
+	top, err := root.Descendant(nil, []string{"top"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	f, err = ParseFields("tags")
+	if err != nil {
+		t.Fatalf("Unexpected fields error: %s", err)
+	}
+	checkFiltered(t, top, f, `{"top":{"tags":["a","b"]}}`)
+
+	for _, expr := range []string{"", "top(", "top)x", "name;;tags", "top()"} {
+		if _, err := ParseFields(expr); err == nil {
+			t.Errorf("Invalid fields accepted: %q", expr)
+		}
+	}
+}
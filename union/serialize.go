@@ -42,13 +42,14 @@ func quote(in string) string {
 	return in
 }
 
-// escapeAndQuote Escapes double quotes, and encloses strings with quotes
+// EscapeAndQuote Escapes double quotes, and encloses strings with quotes
 // Similar to quote() but in addition we first escape any unescaped double
 // quotes and then we add enclosing double quotes for all the instances in
 // quote() and additionally for strings containing any double quotes.  This
 // is needed for when we serialise user data (eg leaf values) or show / load
-// will fail.
-func escapeAndQuote(in string) string {
+// will fail. It is exported for other writers of config, such as the set
+// and delete commands of a diff.
+func EscapeAndQuote(in string) string {
 	in = escapeUnescapedDoubleQuotes(in)
 	if strings.ContainsAny(in, "*}{;\011\012\013\014\015 \"") {
 		in = "\"" + in + "\""
@@ -107,17 +108,24 @@ func (n *node) serializeChildren(b Serializer, cpath []string, lvl int, opts *un
 }
 
 func (n *node) serializeChildrenSkip(b Serializer, cpath []string, lvl int, opts *unionOptions, skipList []string) {
+	if opts.elided(lvl) {
+		return
+	}
 	children := make([]Node, 0)
 	for _, ch := range n.SortedChildren() {
 		if isElemOf(skipList, ch.Name()) {
 			continue
 		}
-		if !opts.includeDefaults && ch.def() {
+		if opts.hideDefault(ch) {
 			continue
 		}
 		children = append(children, ch)
 	}
+	n.serializeNodes(b, children, cpath, lvl, opts)
+}
 
+// serializeNodes serializes the given children of n
+func (n *node) serializeNodes(b Serializer, children []Node, cpath []string, lvl int, opts *unionOptions) {
 	first := true
 
 	for _, ch := range children {
@@ -125,12 +133,22 @@ func (n *node) serializeChildrenSkip(b Serializer, cpath []string, lvl int, opts
 		if !authorize(opts.auth, npath, "read") {
 			continue
 		}
+		chOpts := opts
+		if opts.filter != nil {
+			sel, ok := opts.filter.child(ch, npath, opts)
+			if !ok {
+				continue
+			}
+			filtered := *opts
+			filtered.filter = sel
+			chOpts = &filtered
+		}
 		if !first {
 			b.PrintSep()
 		} else {
 			first = false
 		}
-		ch.serialize(b, npath, lvl, opts)
+		ch.serialize(b, npath, lvl, chOpts)
 	}
 }
 
@@ -147,6 +165,10 @@ func (n *node) Marshal(rootName, encoding string, options ...UnionOption) (strin
 		outb = n.ToNETCONF(rootName, options...)
 	case "xml":
 		outb = n.ToXML(rootName, options...)
+	case "yaml":
+		outb = n.ToYAML(options...)
+	case "cbor":
+		outb = n.ToCBOR(options...)
 	default:
 		return "", errors.New("Invalid encoding requested")
 	}
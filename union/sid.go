@@ -0,0 +1,166 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// YANG Schema Item iDentifiers (SIDs), as assigned in a module's .sid file
+// (RFC 9595), for the CBOR encoding. Data nodes are identified by their
+// schema node path, each step qualified by its module where it differs
+// from the parent's, eg /ietf-system:system/clock/timezone-name.
+
+type SIDMap struct {
+	data       map[string]uint64
+	dataPaths  map[uint64]string
+	identities map[string]uint64
+	//identityNames are the module qualified identities
+	identityNames map[uint64]string
+}
+
+func NewSIDMap() *SIDMap {
+	return &SIDMap{
+		data:          make(map[string]uint64),
+		dataPaths:     make(map[uint64]string),
+		identities:    make(map[string]uint64),
+		identityNames: make(map[uint64]string),
+	}
+}
+
+// sidFile covers both RFC 9595 .sid files and those of the earlier drafts,
+// which were not wrapped in a sid-file container and named the list items.
+type sidFile struct {
+	ModuleName string    `json:"module-name"`
+	Item       []sidItem `json:"item"`
+	Items      []sidItem `json:"items"`
+}
+
+type sidItem struct {
+	Namespace  string      `json:"namespace"`
+	Identifier string      `json:"identifier"`
+	SID        json.Number `json:"sid"`
+}
+
+// Load adds the SIDs of the .sid file read from r.
+func (m *SIDMap) Load(r io.Reader) error {
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return err
+	}
+	if wrapped, ok := doc["ietf-sid-file:sid-file"]; ok {
+		buf = wrapped
+	}
+	var file sidFile
+	if err := json.Unmarshal(buf, &file); err != nil {
+		return err
+	}
+
+	for _, item := range append(file.Item, file.Items...) {
+		sid, err := strconv.ParseUint(item.SID.String(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("Invalid SID for %s: %s", item.Identifier, item.SID)
+		}
+		switch item.Namespace {
+		case "data":
+			m.data[item.Identifier] = sid
+			m.dataPaths[sid] = item.Identifier
+		case "identity":
+			name := item.Identifier
+			if !strings.Contains(name, ":") {
+				name = file.ModuleName + ":" + name
+			}
+			m.identities[name] = sid
+			m.identityNames[sid] = name
+		}
+	}
+	return nil
+}
+
+// LoadSIDFiles returns the SIDs assigned by the given .sid files.
+func LoadSIDFiles(files ...string) (*SIDMap, error) {
+	m := NewSIDMap()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		err = m.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+	}
+	return m, nil
+}
+
+func (m *SIDMap) dataSID(path string) (uint64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	sid, ok := m.data[path]
+	return sid, ok
+}
+
+func (m *SIDMap) dataPath(sid uint64) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	path, ok := m.dataPaths[sid]
+	return path, ok
+}
+
+func (m *SIDMap) identitySID(name string) (uint64, bool) {
+	if m == nil {
+		return 0, false
+	}
+	sid, ok := m.identities[name]
+	return sid, ok
+}
+
+func (m *SIDMap) identityName(sid uint64) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	name, ok := m.identityNames[sid]
+	return name, ok
+}
+
+// sidStep adds the node name of module to the SID identifier path of its
+// parent, whose module is parentModule.
+func sidStep(path, parentModule, module, name string) string {
+	if module != parentModule {
+		return path + "/" + module + ":" + name
+	}
+	return path + "/" + name
+}
+
+// sidPathOf returns the SID identifier path of the schema node of n, and
+// its module.
+func sidPathOf(n Node) (string, string) {
+	var nodes []Node
+	for ; n.Parent() != nil; n = n.Parent() {
+		if _, ok := n.(*ListEntry); ok {
+			continue
+		}
+		nodes = append([]Node{n}, nodes...)
+	}
+	path, module := "", ""
+	for _, n := range nodes {
+		path = sidStep(path, module, n.Module(), n.Name())
+		module = n.Module()
+	}
+	return path, module
+}
@@ -13,6 +13,8 @@ import (
 
 type StringWriter struct {
 	bytes.Buffer
+	//tagDefaults marks default values with a comment
+	tagDefaults bool
 }
 
 func (b *StringWriter) endNode(empty bool, level int) {
@@ -75,7 +77,10 @@ func (b *StringWriter) writeLeafValue(n Node, empty bool, level int, hideSecrets
 		if hideSecrets && n.GetSchema().ConfigdExt().Secret {
 			b.WriteString(quote("********"))
 		} else {
-			b.WriteString(escapeAndQuote(v.Name()))
+			b.WriteString(EscapeAndQuote(v.Name()))
+		}
+		if b.tagDefaults && n.getnode().isDefaultValue() {
+			b.WriteString(" /* default */")
 		}
 		b.WriteByte('\n')
 	}
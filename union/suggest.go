@@ -0,0 +1,68 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"github.com/danos/config/schema"
+	"github.com/danos/utils/pathutil"
+)
+
+// suggestPath adds "did you mean" suggestions to err for the first element
+// of path that isn't in the schema, or isn't one of a leaf's enumerated
+// values, or, if existing is set, isn't in the data.
+// Suggestions from the data are limited to list keys auth allows to be
+// read, and secret keys need the secrets permission too. The values of
+// leaves and leaf-lists in the data are never suggested.
+func (n *node) suggestPath(auth Auther, err error, path []string, existing bool) error {
+	var cur Node = n.specialized
+	for i, elem := range path {
+		parent := schema.Descendant(n.schema, path[:i])
+		if schema.Descendant(parent, []string{elem}) == nil {
+			return schema.WithSuggestions(err, schema.SuggestChild(parent, elem))
+		}
+		switch parent.(type) {
+		case schema.Leaf, schema.LeafList:
+			//Values of enumerations and identityrefs
+			values := schema.GetTypeInfo(parent, parent.Type()).Values
+			if _, ok := values[elem]; !ok && len(values) > 0 {
+				var names []string
+				for v := range values {
+					names = append(names, v)
+				}
+				return schema.WithSuggestions(err, schema.Suggest(elem, names))
+			}
+		}
+		if !existing {
+			continue
+		}
+		ch := cur.Child(elem)
+		if ch == nil {
+			switch parent.(type) {
+			case schema.Leaf, schema.LeafList:
+				return err
+			}
+			var names []string
+			for _, c := range cur.SortedChildren() {
+				chPath := pathutil.CopyAppend(path[:i], c.Name())
+				if c.def() || !authorize(auth, chPath, "read") ||
+					(isSecret(c) && !authorize(auth, chPath, "secrets")) {
+					continue
+				}
+				names = append(names, c.Name())
+			}
+			return schema.WithSuggestions(err, schema.Suggest(elem, names))
+		}
+		cur = ch
+	}
+	return err
+}
+
+// isSecret is true if n, or the key of list entry n, is secret
+func isSecret(n Node) bool {
+	if entry, ok := n.(*ListEntry); ok {
+		return redactListEntry(entry, true)
+	}
+	return n.GetSchema().ConfigdExt().Secret
+}
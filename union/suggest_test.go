@@ -0,0 +1,106 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danos/config/auth"
+	"github.com/danos/config/data"
+)
+
+func TestPathSuggestions(t *testing.T) {
+	root := newCompletionTestTree(t)
+	mustSet(t, root, "top", "entry", "first")
+
+	for _, test := range []struct {
+		path   []string
+		delete bool
+		expect string
+	}{
+		{[]string{"top", "nmae", "y"}, false, "Did you mean: name?"},
+		{[]string{"tpo"}, false, "Did you mean: top?"},
+		{[]string{"top", "mode", "fsat"}, false, "Did you mean: fast?"},
+		{[]string{"top", "entry", "frist"}, true, "Did you mean: first?"},
+		{[]string{"top", "tags", "c"}, true, ""},
+		{[]string{"top", "tags", "bb"}, true, ""},
+		{[]string{"top", "name", "xx"}, true, ""},
+	} {
+		var err error
+		if test.delete {
+			err = root.Delete(nil, test.path, false)
+		} else {
+			err = root.Set(nil, test.path)
+		}
+		if err == nil {
+			t.Errorf("Unexpected success for %v", test.path)
+			continue
+		}
+		if test.expect == "" {
+			if strings.Contains(err.Error(), "Did you mean") {
+				t.Errorf("Unexpected suggestion for %v: %s", test.path, err)
+			}
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("Missing suggestion for %v\n   expect=%s\n   actual=%s",
+				test.path, test.expect, err)
+		}
+	}
+}
+
+const suggestSecretSchema = `
+	container top {
+		leaf password {
+			type string;
+			configd:secret true;
+		}
+		list user {
+			key name;
+			leaf name {
+				type string;
+				configd:secret true;
+			}
+		}
+	}`
+
+func TestPathSuggestionsSecret(t *testing.T) {
+	sch := newTestSchema(t, suggestSecretSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "password", "hunter2")
+	mustSet(t, root, "top", "user", "admin")
+
+	for _, test := range []struct {
+		path        []string
+		showSecrets bool
+		expect      string
+	}{
+		{[]string{"top", "password", "hunter3"}, false, ""},
+		{[]string{"top", "password", "hunter3"}, true, ""},
+		{[]string{"top", "user", "admn"}, false, ""},
+		{[]string{"top", "user", "admn"}, true, "Did you mean: admin?"},
+	} {
+		auther := newTestAuther(
+			auth.NewTestAuther(
+				auth.NewTestRule(auth.Allow, auth.AllOps, "*"),
+			), test.showSecrets)
+		err := root.Delete(auther, test.path, false)
+		if err == nil {
+			t.Errorf("Unexpected success for %v", test.path)
+			continue
+		}
+		if test.expect == "" {
+			if strings.Contains(err.Error(), "Did you mean") {
+				t.Errorf("Unexpected suggestion for %v: %s", test.path, err)
+			}
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("Missing suggestion for %v\n   expect=%s\n   actual=%s",
+				test.path, test.expect, err)
+		}
+	}
+}
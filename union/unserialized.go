@@ -102,6 +102,28 @@ func convertToDataNode(node unserialized, sn yang.Node) (datanode.DataNode, erro
 	return datanode.CreateDataNode(name, children, vals), nil
 }
 
+// unserializedDataTree converts a decoded document, whose top-level node
+// is top, to a data tree, validated against the schema.
+func unserializedDataTree(top unserialized, sn schema.Node) (datanode.DataNode, error) {
+	ukids, err := top.unserializedChildren(sn)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]datanode.DataNode, 0, len(ukids))
+	for _, ch := range ukids {
+		dn, err := convertToDataNode(ch, sn.Child(ch.name()))
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, dn)
+	}
+	datatree := datanode.CreateDataNode(top.name(), children, nil)
+	if err := validateDataNode(datatree, sn); err != nil {
+		return nil, err
+	}
+	return datatree, nil
+}
+
 func validateDataNode(n datanode.DataNode, sn yang.Node) error {
 
 	if _, errs, ok := yang.ValidateSchema(sn, n, false /* dbg */); !ok {
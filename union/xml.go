@@ -20,6 +20,9 @@ import (
 
 type XMLWriter struct {
 	*xml.Encoder
+	annotations bool
+	tagDefaults bool
+	origins     bool
 }
 
 func getPrefixAttributes(n Node, typ yangschema.Type, val string) []xml.Attr {
@@ -45,7 +48,8 @@ func getPrefixAttributes(n Node, typ yangschema.Type, val string) []xml.Attr {
 }
 func (enc *XMLWriter) BeginContainer(n *Container, empty bool, level int) {
 	name := xml.Name{Space: n.Schema.Namespace(), Local: n.Name()}
-	enc.EncodeToken(xml.StartElement{Name: name})
+	enc.EncodeToken(xml.StartElement{Name: name,
+		Attr: enc.annotationAttributes(n, nil)})
 }
 func (enc *XMLWriter) EndContainer(n *Container, empty bool, level int) {
 	name := xml.Name{Space: n.Schema.Namespace(), Local: n.Name()}
@@ -57,7 +61,8 @@ func (enc *XMLWriter) BeginListEntry(n *ListEntry, empty bool, level int, hideSe
 	sch := n.Schema
 	prefixes := getPrefixAttributes(n, sch.Type(), n.Data().Name())
 	pname := xml.Name{Space: sch.Namespace(), Local: n.parent.Name()}
-	enc.EncodeToken(xml.StartElement{Name: pname})
+	enc.EncodeToken(xml.StartElement{Name: pname,
+		Attr: enc.annotationAttributes(n, nil)})
 
 	//create list 'key' nodes
 	//TODO: fix this for multi part keys
@@ -83,7 +88,9 @@ func (enc *XMLWriter) WriteLeafValue(n *Leaf, empty bool, level int, hideSecrets
 func (enc *XMLWriter) writeLeafValue(n Node, empty bool, level int, hideSecrets bool) {
 	name := xml.Name{Space: n.GetSchema().Namespace(), Local: n.Name()}
 	if empty {
-		enc.EncodeToken(xml.StartElement{Name: name})
+		attrs := enc.annotationAttributes(n, nil)
+		enc.EncodeToken(xml.StartElement{Name: name,
+			Attr: enc.defaultAttributes(n, attrs)})
 		enc.EncodeToken(xml.EndElement{Name: name})
 		return
 	}
@@ -95,6 +102,12 @@ func (enc *XMLWriter) writeLeafValue(n Node, empty bool, level int, hideSecrets
 		//just ignore them, there is no error path
 		//here.
 		prefixes := getPrefixAttributes(n, v.GetSchema().Type(), v.Name())
+		if _, ok := n.(*LeafList); ok {
+			prefixes = enc.annotationAttributes(v, prefixes)
+		} else {
+			prefixes = enc.annotationAttributes(n, prefixes)
+		}
+		prefixes = enc.defaultAttributes(n, prefixes)
 		enc.EncodeToken(xml.StartElement{Name: name, Attr: prefixes})
 		if hide {
 			enc.EncodeToken(xml.CharData("********"))
@@ -162,8 +175,17 @@ func (n *node) addEnclosingEndElements(enc *XMLWriter) {
 // For 'xml', we just return the node and entries under it, wrapped
 // in the tag for the rootName.
 func (n *node) ToXML(rootName string, options ...UnionOption) []byte {
+	var opts unionOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
 	var b bytes.Buffer
-	enc := &XMLWriter{xml.NewEncoder(&b)}
+	enc := &XMLWriter{
+		Encoder:     xml.NewEncoder(&b),
+		annotations: opts.includeAnnotations,
+		tagDefaults: opts.tagDefaults(),
+		origins:     opts.includeOrigin,
+	}
 	enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: rootName}})
 	n.Serialize(enc, nil, options...)
 	enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: rootName}})
@@ -177,8 +199,17 @@ func (n *node) ToXML(rootName string, options ...UnionOption) []byte {
 // eg if <n> represents '/interfaces/dataplane/dp0s1/address', we need to return
 // tags for interfaces, dataplane *and* tagnode (dp0s1)
 func (n *node) ToNETCONF(rootName string, options ...UnionOption) []byte {
+	var opts unionOptions
+	for _, opt := range options {
+		opt(&opts)
+	}
 	var b bytes.Buffer
-	enc := &XMLWriter{xml.NewEncoder(&b)}
+	enc := &XMLWriter{
+		Encoder:     xml.NewEncoder(&b),
+		annotations: opts.includeAnnotations,
+		tagDefaults: opts.tagDefaults(),
+		origins:     opts.includeOrigin,
+	}
 
 	enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: rootName}})
 	n.addEnclosingStartElements(enc)
@@ -197,10 +228,18 @@ func UnmarshalXML(schemaRoot schema.Node, xml_input []byte) (root Node, err erro
 		return nil, fmt.Errorf("Invalid schema provided")
 	}
 
+	anns, err := xmlInputAnnotations(schemaRoot, xml_input)
+	if err != nil {
+		return nil, err
+	}
+
 	datatree, err := encoding.UnmarshalXML(schemaRoot, xml_input)
 	if err != nil {
 		return nil, err
 	}
 
-	return root, yangDataIntoTree(root, datatree)
+	if err := yangDataIntoTree(root, datatree); err != nil {
+		return nil, err
+	}
+	return root, applyAnnotations(root, anns)
 }
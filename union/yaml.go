@@ -0,0 +1,409 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	"github.com/danos/yang/data/datanode"
+	yang "github.com/danos/yang/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// YAML encoding follows RFC 7951: member names are qualified by their
+// module where it differs from the parent's, lists are sequences of
+// mappings and an empty leaf is [null].
+
+type YAMLWriter struct {
+	bytes.Buffer
+	moduleName []string
+	depth      int
+}
+
+func (b *YAMLWriter) indent(depth int) {
+	for i := 0; i < depth; i++ {
+		b.WriteString("  ")
+	}
+}
+
+// beginMember writes the indented, module qualified name of n.
+func (b *YAMLWriter) beginMember(n Node) {
+	name := n.Module()
+	prefix := len(b.moduleName) == 0 ||
+		name != b.moduleName[len(b.moduleName)-1]
+	b.moduleName = append(b.moduleName, name)
+
+	b.indent(b.depth)
+	if prefix {
+		b.WriteString(name)
+		b.WriteByte(':')
+	}
+	b.WriteString(n.Name())
+	b.WriteByte(':')
+}
+
+func (b *YAMLWriter) popName() {
+	if len(b.moduleName) > 0 {
+		b.moduleName = b.moduleName[:len(b.moduleName)-1]
+	}
+}
+
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "y": true,
+	"n": true, "on": true, "off": true, "null": true,
+}
+
+// yamlQuote leaves a value unquoted only where no YAML parser could take
+// it for anything other than a string.
+func yamlQuote(in string) string {
+	if in == "" || yamlReserved[strings.ToLower(in)] ||
+		strings.HasSuffix(in, ":") {
+		return strconv.Quote(in)
+	}
+	for i, c := range in {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i == 0:
+			return strconv.Quote(in)
+		case c >= '0' && c <= '9', strings.ContainsRune("_.:/@+-", c):
+		default:
+			return strconv.Quote(in)
+		}
+	}
+	return in
+}
+
+func (b *YAMLWriter) writeValue(n Node) {
+	typ := n.GetSchema().Type()
+	if utyp, ok := typ.(schema.Union); ok {
+		typ = utyp.MatchType(nil, []string{}, n.Name())
+	}
+
+	switch t := typ.(type) {
+	case schema.Integer:
+		if t.BitWidth() > 32 {
+			b.WriteString(strconv.Quote(n.Name()))
+		} else {
+			b.WriteString(n.Name())
+		}
+	case schema.Uinteger:
+		if t.BitWidth() > 32 {
+			b.WriteString(strconv.Quote(n.Name()))
+		} else {
+			b.WriteString(n.Name())
+		}
+	case schema.Boolean:
+		b.WriteString(n.Name())
+	default:
+		b.WriteString(yamlQuote(n.Name()))
+	}
+}
+
+func (b *YAMLWriter) BeginContainer(n *Container, empty bool, level int) {
+	b.beginMember(n)
+	if empty {
+		b.WriteString(" {}\n")
+		return
+	}
+	b.WriteByte('\n')
+	b.depth++
+}
+
+func (b *YAMLWriter) EndContainer(n *Container, empty bool, level int) {
+	if !empty {
+		b.depth--
+	}
+	b.popName()
+}
+
+func (b *YAMLWriter) BeginList(n *List, empty bool, level int) {
+	b.beginMember(n)
+	b.WriteByte('\n')
+	b.depth++
+}
+
+func (b *YAMLWriter) EndList(n *List, empty bool, level int) {
+	b.depth--
+	b.popName()
+}
+
+func (b *YAMLWriter) BeginListEntry(n *ListEntry, empty bool, level int, hideSecrets bool) {
+	b.indent(b.depth)
+	b.WriteString("- ")
+	b.WriteString(n.Schema.Keys()[0])
+	b.WriteString(": ")
+	if redactListEntry(n, hideSecrets) {
+		b.WriteString(yamlQuote("********"))
+	} else {
+		b.writeValue(n)
+	}
+	b.WriteByte('\n')
+	b.depth++
+}
+
+func (b *YAMLWriter) EndListEntry(n *ListEntry, empty bool, level int) {
+	b.depth--
+}
+
+func (b *YAMLWriter) BeginLeaf(n *Leaf, empty bool, level int, hideSecrets bool) {
+	b.beginMember(n)
+	b.WriteByte(' ')
+}
+
+func (b *YAMLWriter) WriteLeafValue(n *Leaf, empty bool, level int, hideSecrets bool) {
+	vals := n.SortedChildren()
+	switch {
+	case len(vals) == 0:
+		b.WriteString("[null]")
+	case hideSecrets && n.GetSchema().ConfigdExt().Secret:
+		b.WriteString(yamlQuote("********"))
+	default:
+		b.writeValue(vals[0])
+	}
+	b.WriteByte('\n')
+}
+
+func (b *YAMLWriter) EndLeaf(n *Leaf, empty bool, level int) {
+	b.popName()
+}
+
+func (b *YAMLWriter) BeginLeafList(n *LeafList, empty bool, level int, hideSecrets bool) {
+	b.beginMember(n)
+}
+
+func (b *YAMLWriter) WriteLeafListValues(n *LeafList, empty bool, level int, hideSecrets bool) {
+	vals := n.SortedChildren()
+	if len(vals) == 0 {
+		b.WriteString(" []\n")
+		return
+	}
+	b.WriteByte('\n')
+	hide := hideSecrets && n.GetSchema().ConfigdExt().Secret
+	for _, v := range vals {
+		b.indent(b.depth + 1)
+		b.WriteString("- ")
+		if hide {
+			b.WriteString(yamlQuote("********"))
+		} else {
+			b.writeValue(v)
+		}
+		b.WriteByte('\n')
+	}
+}
+
+func (b *YAMLWriter) EndLeafList(n *LeafList, empty bool, level int) {
+	b.popName()
+}
+
+func (b *YAMLWriter) PrintSep() {}
+
+func (n *node) ToYAML(options ...UnionOption) []byte {
+	var b YAMLWriter
+	n.Serialize(&b, nil, options...)
+	if b.Len() == 0 {
+		b.WriteString("{}\n")
+	}
+	return b.Bytes()
+}
+
+// yamlUnserialized is a node of a decoded YAML document, converted to a
+// data tree by convertToDataNode.
+type yamlUnserialized struct {
+	member string
+	node   *yaml.Node
+	//sn is the schema of the node, needed to decode identities
+	sn   yang.Node
+	path []string
+}
+
+func newYAMLTypeError(path []string, node *yaml.Node, expected string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Path = pathutil.Pathstr(path)
+	err.Message = fmt.Sprintf("Line %d: %s expected", node.Line, expected)
+	return err
+}
+
+// resolveYAML follows aliases to the node they refer to.
+func resolveYAML(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func isYAMLNull(node *yaml.Node) bool {
+	return node == nil ||
+		(node.Kind == yaml.ScalarNode && node.Tag == "!!null")
+}
+
+func (u *yamlUnserialized) name() string {
+	return u.member
+}
+
+func (u *yamlUnserialized) scalar(node *yaml.Node) (string, error) {
+	node = resolveYAML(node)
+	if isYAMLNull(node) {
+		return "", nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", newYAMLTypeError(u.path, node, "Value")
+	}
+	typ, _ := u.sn.Type().(schema.Type)
+	return stripIdentityPrefix(typ, node.Value), nil
+}
+
+func (u *yamlUnserialized) values() ([]string, error) {
+	node := resolveYAML(u.node)
+	switch {
+	case isYAMLNull(node):
+		return []string{}, nil
+	case node.Kind == yaml.SequenceNode:
+		vals := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			val, err := u.scalar(item)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, val)
+		}
+		return vals, nil
+	}
+	val, err := u.scalar(node)
+	if err != nil {
+		return nil, err
+	}
+	return []string{val}, nil
+}
+
+func (u *yamlUnserialized) unserializedChildren(sn yang.Node) ([]unserialized, error) {
+	node := resolveYAML(u.node)
+	if isYAMLNull(node) {
+		return nil, nil
+	}
+	if l, ok := sn.(schema.List); ok {
+		return u.listEntries(l, node)
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil, newYAMLTypeError(u.path, node, "Mapping")
+	}
+
+	children := make([]unserialized, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		member := node.Content[i].Value
+		name := jsonLocalName(member)
+		csn := sn.Child(name)
+		if csn != nil && name != member &&
+			member[:len(member)-len(name)-1] != csn.Module() {
+			csn = nil
+		}
+		if csn == nil {
+			err := mgmterror.NewUnknownElementApplicationError(name)
+			err.Path = pathutil.Pathstr(u.path)
+			if parent, ok := sn.(schema.Node); ok {
+				return nil, schema.WithSuggestions(err,
+					schema.SuggestChild(parent, name))
+			}
+			return nil, err
+		}
+		children = append(children, &yamlUnserialized{
+			member: name,
+			node:   node.Content[i+1],
+			sn:     csn,
+			path:   pathutil.CopyAppend(u.path, name),
+		})
+	}
+	return children, nil
+}
+
+// listEntries returns the entries of a list, named by their key.
+func (u *yamlUnserialized) listEntries(
+	sn schema.List,
+	node *yaml.Node,
+) ([]unserialized, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, newYAMLTypeError(u.path, node, "Sequence")
+	}
+	keyname := sn.Keys()[0]
+	entries := make([]unserialized, 0, len(node.Content))
+	for _, item := range node.Content {
+		item = resolveYAML(item)
+		if item.Kind != yaml.MappingNode {
+			return nil, newYAMLTypeError(u.path, item, "Mapping")
+		}
+		var key *yaml.Node
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			if jsonLocalName(item.Content[i].Value) == keyname {
+				key = item.Content[i+1]
+				break
+			}
+		}
+		if key == nil {
+			return nil, yang.NewMissingKeyError([]string{sn.Name(), keyname})
+		}
+		entry := sn.Child(keyname)
+		keyval, err := (&yamlUnserialized{
+			sn:   entry.Child(keyname),
+			path: u.path,
+		}).scalar(key)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, &yamlUnserialized{
+			member: keyval,
+			node:   item,
+			sn:     entry,
+			path:   pathutil.CopyAppend(u.path, keyval),
+		})
+	}
+	return entries, nil
+}
+
+// yamlDataTree converts a YAML document to a data tree, validated
+// against the schema.
+func yamlDataTree(sn schema.Node, input []byte) (datanode.DataNode, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(input, &doc); err != nil {
+		merr := mgmterror.NewMalformedMessageError()
+		merr.Message = err.Error()
+		return nil, merr
+	}
+	top := &yamlUnserialized{member: "root", sn: sn, path: []string{}}
+	if len(doc.Content) > 0 {
+		top.node = doc.Content[0]
+	}
+	return unserializedDataTree(top, sn)
+}
+
+// UnmarshalYAML creates a tree from a YAML document encoded as ToYAML
+// does.
+func UnmarshalYAML(schemaRoot schema.Node, yamlInput []byte) (Node, error) {
+	root := NewNode(data.New("root"), data.New("root"), schemaRoot, nil, 0)
+	if root == nil {
+		err := mgmterror.NewOperationFailedApplicationError()
+		err.Message = "Invalid schema provided"
+		return nil, err
+	}
+
+	if err := UnmarshalYAMLIntoNode(root, yamlInput); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// UnmarshalYAMLIntoNode merges a YAML document into ut.
+func UnmarshalYAMLIntoNode(ut Node, yamlInput []byte) error {
+	datatree, err := yamlDataTree(ut.GetSchema(), yamlInput)
+	if err != nil {
+		return err
+	}
+	return yangDataIntoTree(ut, datatree)
+}
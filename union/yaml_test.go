@@ -0,0 +1,108 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danos/config/data"
+)
+
+const yamlSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf flag {
+			type empty;
+		}
+		leaf big {
+			type uint64;
+		}
+		leaf enabled {
+			type boolean;
+		}
+		leaf-list tags {
+			type string;
+			ordered-by user;
+		}
+		list rule {
+			key id;
+			ordered-by user;
+			leaf id {
+				type string;
+			}
+			leaf action {
+				type string;
+			}
+		}
+	}`
+
+func TestYAMLRoundTrip(t *testing.T) {
+	sch := newTestSchema(t, yamlSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	mustSet(t, root, "top", "name", "on: off")
+	mustSet(t, root, "top", "flag")
+	mustSet(t, root, "top", "big", "5")
+	mustSet(t, root, "top", "enabled", "true")
+	mustSet(t, root, "top", "tags", "b")
+	mustSet(t, root, "top", "tags", "a")
+	mustSet(t, root, "top", "rule", "z", "action", "drop")
+	mustSet(t, root, "top", "rule", "a")
+
+	expected := `test-union:top:
+  big: "5"
+  enabled: true
+  flag: [null]
+  name: "on: off"
+  rule:
+    - id: z
+      action: drop
+    - id: a
+  tags:
+    - b
+    - a
+`
+	actual := string(root.ToYAML())
+	if actual != expected {
+		t.Fatalf("Unexpected YAML encoding\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+
+	decoded, err := UnmarshalYAML(sch, []byte(actual))
+	if err != nil {
+		t.Fatalf("Unexpected unmarshal error: %s", err)
+	}
+	if again := string(decoded.ToYAML()); again != expected {
+		t.Errorf("YAML did not round trip\n   expect=%s\n   actual=%s",
+			expected, again)
+	}
+}
+
+func TestYAMLDecodeErrors(t *testing.T) {
+	sch := newTestSchema(t, yamlSchema)
+	for _, test := range []struct {
+		input  string
+		expect string
+	}{
+		{"test-union:top:\n  nmae: x\n", "Did you mean: name?"},
+		{"other:top: {}\n", "top"},
+		{"test-union:top:\n  rule:\n    - action: drop\n", "id"},
+		{"test-union:top:\n  name:\n    a: b\n", "Value expected"},
+		{"test-union:top:\n  enabled: maybe\n", "maybe"},
+		{"test-union:top: [\n", "yaml"},
+	} {
+		_, err := UnmarshalYAML(sch, []byte(test.input))
+		if err == nil {
+			t.Errorf("Unexpected success decoding %q", test.input)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("Unexpected error decoding %q\n   expect=%s\n   actual=%s",
+				test.input, test.expect, err)
+		}
+	}
+}
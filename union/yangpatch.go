@@ -0,0 +1,637 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+	"github.com/danos/mgmterror"
+	"github.com/danos/utils/pathutil"
+	yang "github.com/danos/yang/schema"
+)
+
+// YANG Patch (RFC 8072) support. A patch is parsed, and each edit's
+// target and value resolved against the schema, before anything is
+// applied; the edits are then applied in order as a single change which
+// is reverted if any edit fails.
+
+const yangPatchNamespace = "urn:ietf:params:xml:ns:yang:ietf-yang-patch"
+
+type PatchOperation string
+
+const (
+	PatchCreate  PatchOperation = "create"
+	PatchDelete  PatchOperation = "delete"
+	PatchInsert  PatchOperation = "insert"
+	PatchMerge   PatchOperation = "merge"
+	PatchMove    PatchOperation = "move"
+	PatchReplace PatchOperation = "replace"
+	PatchRemove  PatchOperation = "remove"
+)
+
+type PatchEdit struct {
+	EditId    string
+	Operation PatchOperation
+	//Target and Point are the resolved data paths
+	Target []string
+	Point  []string
+	Where  Position
+	//values are the paths to be set for the edit's value
+	values [][]string
+}
+
+type YangPatch struct {
+	PatchId string
+	Comment string
+	Edits   []*PatchEdit
+}
+
+func newPatchError(edit, msg string) error {
+	err := mgmterror.NewInvalidValueApplicationError()
+	err.Message = fmt.Sprintf("Edit %s: %s", edit, msg)
+	return err
+}
+
+func newMalformedPatchError(err error) error {
+	mErr := mgmterror.NewMalformedMessageError()
+	mErr.Message = err.Error()
+	return mErr
+}
+
+// patchSegment is one resolved element of a data resource identifier
+type patchSegment struct {
+	sn    schema.Node
+	name  string
+	key   string
+	keyed bool
+}
+
+// resolvePatchPath resolves a RESTCONF data resource identifier, eg
+// "/module:top/list=key/leaf", to its path in the tree.
+func resolvePatchPath(
+	root schema.Node,
+	target string,
+) ([]patchSegment, []string, error) {
+	var segs []patchSegment
+	path := []string{}
+	sn := root
+	target = strings.Trim(target, "/")
+	if target == "" {
+		return segs, path, nil
+	}
+	for _, elem := range strings.Split(target, "/") {
+		seg := patchSegment{name: elem}
+		if i := strings.Index(elem, "="); i >= 0 {
+			seg.name = elem[:i]
+			key, err := url.PathUnescape(elem[i+1:])
+			if err != nil {
+				return nil, nil, err
+			}
+			//Only single keys are supported
+			seg.key, seg.keyed = key, true
+		}
+		if i := strings.Index(seg.name, ":"); i >= 0 {
+			seg.name = seg.name[i+1:]
+		}
+		seg.sn = sn.SchemaChild(seg.name)
+		if seg.sn == nil {
+			err := mgmterror.NewUnknownElementApplicationError(seg.name)
+			err.Path = pathutil.Pathstr(path)
+			return nil, nil, err
+		}
+		path = append(path, seg.name)
+		switch v := seg.sn.(type) {
+		case schema.List:
+			if !seg.keyed {
+				return nil, nil, yang.NewMissingKeyError(path)
+			}
+			path = append(path, seg.key)
+			sn = v.SchemaChild(seg.key)
+		case schema.LeafList:
+			if seg.keyed {
+				path = append(path, seg.key)
+			}
+			sn = v
+		default:
+			if seg.keyed {
+				return nil, nil, yang.NewSchemaMismatchError(seg.key, path)
+			}
+			sn = v
+		}
+		segs = append(segs, seg)
+	}
+	return segs, path, nil
+}
+
+// jsonKeyValue returns the list key in the form RFC 7951 encodes it
+func jsonKeyValue(sn schema.Node, key string) interface{} {
+	typ := sn.Type()
+	if utyp, ok := typ.(schema.Union); ok {
+		typ = utyp.MatchType(nil, []string{}, key)
+	}
+	switch t := typ.(type) {
+	case schema.Integer:
+		if t.BitWidth() <= 32 {
+			return json.Number(key)
+		}
+	case schema.Uinteger:
+		if t.BitWidth() <= 32 {
+			return json.Number(key)
+		}
+	case schema.Boolean:
+		return key == "true"
+	}
+	return key
+}
+
+func listKeySchema(seg patchSegment) (string, schema.Node) {
+	l := seg.sn.(schema.List)
+	keyname := l.Keys()[0]
+	entry := l.SchemaChild(seg.key)
+	if entry == nil {
+		return keyname, nil
+	}
+	return keyname, entry.SchemaChild(keyname)
+}
+
+// wrapJSONValue encloses the value of an edit in its ancestors so it can
+// be decoded as a complete RFC 7951 document.
+func wrapJSONValue(
+	parents []patchSegment,
+	value map[string]interface{},
+) map[string]interface{} {
+	doc := value
+	for i := len(parents) - 1; i >= 0; i-- {
+		seg := parents[i]
+		member := seg.sn.Module() + ":" + seg.name
+		if _, ok := seg.sn.(schema.List); ok {
+			keyname, ksn := listKeySchema(seg)
+			var key interface{} = seg.key
+			if ksn != nil {
+				key = jsonKeyValue(ksn, seg.key)
+			}
+			doc[keyname] = key
+			doc = map[string]interface{}{member: []interface{}{doc}}
+			continue
+		}
+		doc = map[string]interface{}{member: doc}
+	}
+	return doc
+}
+
+func encodeXMLElement(enc *xml.Encoder, elem *xmlElement) {
+	var attrs []xml.Attr
+	for _, attr := range elem.attrs {
+		switch {
+		case attr.Name.Space == "xmlns":
+			//Keep prefix declarations, eg for identityref values
+			attrs = append(attrs, xml.Attr{
+				Name:  xml.Name{Local: "xmlns:" + attr.Name.Local},
+				Value: attr.Value})
+		case attr.Name.Space == "" && attr.Name.Local != "xmlns":
+			attrs = append(attrs, attr)
+		}
+	}
+	enc.EncodeToken(xml.StartElement{Name: elem.name, Attr: attrs})
+	if len(elem.children) == 0 {
+		enc.EncodeToken(xml.CharData(elem.text))
+	}
+	for _, ch := range elem.children {
+		encodeXMLElement(enc, ch)
+	}
+	enc.EncodeToken(xml.EndElement{Name: elem.name})
+}
+
+// wrapXMLValue encloses the value of an edit in its ancestors, as
+// UnmarshalXML expects, in a <data> element.
+func wrapXMLValue(parents []patchSegment, value *xmlElement) []byte {
+	var b bytes.Buffer
+	enc := xml.NewEncoder(&b)
+	enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "data"}})
+	for _, seg := range parents {
+		name := xml.Name{Space: seg.sn.Namespace(), Local: seg.name}
+		enc.EncodeToken(xml.StartElement{Name: name})
+		if _, ok := seg.sn.(schema.List); ok {
+			keyname, _ := listKeySchema(seg)
+			kname := xml.Name{Space: seg.sn.Namespace(), Local: keyname}
+			enc.EncodeToken(xml.StartElement{Name: kname})
+			enc.EncodeToken(xml.CharData(seg.key))
+			enc.EncodeToken(xml.EndElement{Name: kname})
+		}
+	}
+	for _, ch := range value.children {
+		encodeXMLElement(enc, ch)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		name := xml.Name{Space: parents[i].sn.Namespace(), Local: parents[i].name}
+		enc.EncodeToken(xml.EndElement{Name: name})
+	}
+	enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "data"}})
+	enc.Flush()
+	return b.Bytes()
+}
+
+func collectDataPaths(n *data.Node, path []string, out [][]string) [][]string {
+	if n.NumChildren() == 0 {
+		return append(out, path)
+	}
+	children := n.Children()
+	sort.Sort(data.ByUser(children))
+	for _, ch := range children {
+		out = collectDataPaths(ch, pathutil.CopyAppend(path, ch.Name()), out)
+	}
+	return out
+}
+
+// valuePaths returns the paths to set in order to create the target
+// with the decoded value.
+func valuePaths(editId string, tree Node, target []string) ([][]string, error) {
+	d := tree.Data()
+	for _, elem := range target {
+		d = d.Child(elem)
+		if d == nil {
+			return nil, newPatchError(editId, "value does not match target")
+		}
+	}
+	return collectDataPaths(d, target, nil), nil
+}
+
+func needsValue(op PatchOperation) bool {
+	switch op {
+	case PatchCreate, PatchInsert, PatchMerge, PatchReplace:
+		return true
+	}
+	return false
+}
+
+func newPatchEdit(
+	sch schema.Node,
+	editId, operation, target, point, where string,
+) (*PatchEdit, []patchSegment, error) {
+	edit := &PatchEdit{
+		EditId:    editId,
+		Operation: PatchOperation(operation),
+		Where:     Position(where),
+	}
+	switch edit.Operation {
+	case PatchCreate, PatchDelete, PatchInsert, PatchMerge,
+		PatchMove, PatchReplace, PatchRemove:
+	default:
+		return nil, nil, newPatchError(editId,
+			fmt.Sprintf("invalid operation %s", operation))
+	}
+	segs, path, err := resolvePatchPath(sch, target)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(segs) == 0 {
+		//The datastore root ("/") can't be replaced as a whole
+		return nil, nil, newPatchError(editId, "invalid target")
+	}
+	edit.Target = path
+	if point != "" {
+		if _, edit.Point, err = resolvePatchPath(sch, point); err != nil {
+			return nil, nil, err
+		}
+	}
+	return edit, segs, nil
+}
+
+type jsonPatchEdit struct {
+	EditId    string          `json:"edit-id"`
+	Operation string          `json:"operation"`
+	Target    string          `json:"target"`
+	Point     string          `json:"point"`
+	Where     string          `json:"where"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type jsonYangPatch struct {
+	Patch *struct {
+		PatchId string          `json:"patch-id"`
+		Comment string          `json:"comment"`
+		Edit    []jsonPatchEdit `json:"edit"`
+	} `json:"ietf-yang-patch:yang-patch"`
+}
+
+// ParseYangPatchJSON parses an RFC 7951 encoded ietf-yang-patch:yang-patch
+// document, resolving it against the schema.
+func ParseYangPatchJSON(ms schema.ModelSet, input []byte) (*YangPatch, error) {
+	var in jsonYangPatch
+	if err := json.Unmarshal(input, &in); err != nil {
+		return nil, newMalformedPatchError(err)
+	}
+	if in.Patch == nil {
+		return nil, newMalformedPatchError(
+			fmt.Errorf("missing ietf-yang-patch:yang-patch"))
+	}
+	patch := &YangPatch{PatchId: in.Patch.PatchId, Comment: in.Patch.Comment}
+	for _, e := range in.Patch.Edit {
+		edit, segs, err := newPatchEdit(ms,
+			e.EditId, e.Operation, e.Target, e.Point, e.Where)
+		if err != nil {
+			return nil, err
+		}
+		if needsValue(edit.Operation) {
+			var value map[string]interface{}
+			dec := json.NewDecoder(bytes.NewReader(e.Value))
+			dec.UseNumber()
+			if err := dec.Decode(&value); err != nil || value == nil {
+				return nil, newPatchError(e.EditId, "missing value")
+			}
+			doc, err := json.Marshal(
+				wrapJSONValue(segs[:len(segs)-1], value))
+			if err != nil {
+				return nil, err
+			}
+			tree, err := UnmarshalRFC7951(ms, doc)
+			if err != nil {
+				return nil, err
+			}
+			if edit.values, err = valuePaths(e.EditId, tree, edit.Target); err != nil {
+				return nil, err
+			}
+		}
+		patch.Edits = append(patch.Edits, edit)
+	}
+	return patch, nil
+}
+
+func childText(elem *xmlElement, name string) string {
+	for _, ch := range elem.children {
+		if ch.name.Local == name {
+			return strings.TrimSpace(ch.text)
+		}
+	}
+	return ""
+}
+
+func childElement(elem *xmlElement, name string) *xmlElement {
+	for _, ch := range elem.children {
+		if ch.name.Local == name {
+			return ch
+		}
+	}
+	return nil
+}
+
+// ParseYangPatchXML parses an XML encoded yang-patch document, resolving
+// it against the schema.
+func ParseYangPatchXML(ms schema.ModelSet, input []byte) (*YangPatch, error) {
+	doc, err := parseXMLElements(input)
+	if err != nil {
+		return nil, newMalformedPatchError(err)
+	}
+	if len(doc.children) != 1 || doc.children[0].name.Local != "yang-patch" ||
+		doc.children[0].name.Space != yangPatchNamespace {
+		return nil, newMalformedPatchError(fmt.Errorf("missing yang-patch"))
+	}
+	top := doc.children[0]
+	patch := &YangPatch{
+		PatchId: childText(top, "patch-id"),
+		Comment: childText(top, "comment"),
+	}
+	for _, e := range top.children {
+		if e.name.Local != "edit" {
+			continue
+		}
+		editId := childText(e, "edit-id")
+		edit, segs, err := newPatchEdit(ms, editId,
+			childText(e, "operation"), childText(e, "target"),
+			childText(e, "point"), childText(e, "where"))
+		if err != nil {
+			return nil, err
+		}
+		if needsValue(edit.Operation) {
+			value := childElement(e, "value")
+			if value == nil {
+				return nil, newPatchError(editId, "missing value")
+			}
+			tree, err := UnmarshalXML(ms,
+				wrapXMLValue(segs[:len(segs)-1], value))
+			if err != nil {
+				return nil, err
+			}
+			if edit.values, err = valuePaths(editId, tree, edit.Target); err != nil {
+				return nil, err
+			}
+		}
+		patch.Edits = append(patch.Edits, edit)
+	}
+	return patch, nil
+}
+
+type EditStatus struct {
+	EditId string
+	Error  error
+}
+
+// YangPatchStatus is the result of applying a patch. The edits up to
+// and including the first failure are reported.
+type YangPatchStatus struct {
+	PatchId string
+	Edits   []EditStatus
+}
+
+func (s *YangPatchStatus) Ok() bool {
+	for _, e := range s.Edits {
+		if e.Error != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *node) positionPatchEdit(auth Auther, edit *PatchEdit) error {
+	if len(edit.Target) < 2 {
+		return newNotUserOrderedError(edit.Target)
+	}
+	parentPath := edit.Target[:len(edit.Target)-1]
+	where := edit.Where
+	if where == "" {
+		where = PositionLast
+	}
+	var point string
+	switch where {
+	case PositionBefore, PositionAfter:
+		if len(edit.Point) != len(edit.Target) ||
+			pathutil.Pathstr(edit.Point[:len(edit.Point)-1]) !=
+				pathutil.Pathstr(parentPath) {
+			return newPatchError(edit.EditId, "point is not a sibling of target")
+		}
+		point = edit.Point[len(edit.Point)-1]
+	}
+	return n.positionEntry(auth, edit.Target, where, point)
+}
+
+func (n *node) setPatchValues(auth Auther, edit *PatchEdit) error {
+	for _, path := range edit.values {
+		if n.configExists(path) {
+			continue
+		}
+		if err := n.Set(auth, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *node) applyPatchEdit(auth Auther, edit *PatchEdit) error {
+	exists := n.configExists(edit.Target)
+	switch edit.Operation {
+	case PatchCreate:
+		if exists {
+			return newDataExistsError(edit.Target)
+		}
+		return n.setPatchValues(auth, edit)
+	case PatchInsert:
+		if exists {
+			return newDataExistsError(edit.Target)
+		}
+		if err := n.setPatchValues(auth, edit); err != nil {
+			return err
+		}
+		return n.positionPatchEdit(auth, edit)
+	case PatchMove:
+		if !exists {
+			return newDataMissingError(edit.Target)
+		}
+		return n.positionPatchEdit(auth, edit)
+	case PatchDelete:
+		if !exists {
+			return newDataMissingError(edit.Target)
+		}
+		return n.Delete(auth, edit.Target, DontCheckAuth)
+	case PatchRemove:
+		if !exists {
+			return nil
+		}
+		return n.Delete(auth, edit.Target, DontCheckAuth)
+	case PatchReplace:
+		if exists {
+			if err := n.Delete(auth, edit.Target, DontCheckAuth); err != nil {
+				return err
+			}
+		}
+		return n.setPatchValues(auth, edit)
+	default:
+		return n.setPatchValues(auth, edit)
+	}
+}
+
+// ApplyYangPatch applies the edits in order. Either all edits succeed or
+// the tree is left unchanged; the status reports which edit failed.
+func (n *node) ApplyYangPatch(auth Auther, patch *YangPatch) *YangPatchStatus {
+	status := &YangPatchStatus{PatchId: patch.PatchId}
+	n.withRollback(func() error {
+		for _, edit := range patch.Edits {
+			err := n.applyPatchEdit(auth, edit)
+			status.Edits = append(status.Edits,
+				EditStatus{EditId: edit.EditId, Error: err})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return status
+}
+
+// patchErrorInfo is implemented by the mgmterror errors
+type patchErrorInfo interface {
+	GetType() string
+	GetTag() string
+	GetPath() string
+	GetMessage() string
+}
+
+type patchError struct {
+	XMLName xml.Name `xml:"error" json:"-"`
+	Type    string   `xml:"error-type" json:"error-type"`
+	Tag     string   `xml:"error-tag" json:"error-tag"`
+	Path    string   `xml:"error-path,omitempty" json:"error-path,omitempty"`
+	Message string   `xml:"error-message,omitempty" json:"error-message,omitempty"`
+}
+
+func newPatchErrorReport(err error) patchError {
+	if info, ok := err.(patchErrorInfo); ok {
+		return patchError{
+			Type:    info.GetType(),
+			Tag:     info.GetTag(),
+			Path:    info.GetPath(),
+			Message: info.GetMessage(),
+		}
+	}
+	return patchError{
+		Type:    "application",
+		Tag:     "operation-failed",
+		Message: err.Error(),
+	}
+}
+
+type patchErrors struct {
+	Error []patchError `xml:"error" json:"error"`
+}
+
+type patchEditStatus struct {
+	XMLName xml.Name     `xml:"edit" json:"-"`
+	EditId  string       `xml:"edit-id" json:"edit-id"`
+	Errors  *patchErrors `xml:"errors,omitempty" json:"errors,omitempty"`
+}
+
+type patchStatus struct {
+	XMLName    xml.Name      `xml:"urn:ietf:params:xml:ns:yang:ietf-yang-patch yang-patch-status" json:"-"`
+	PatchId    string        `xml:"patch-id,omitempty" json:"patch-id,omitempty"`
+	Ok         *struct{}     `xml:"ok" json:"-"`
+	JSONOk     []interface{} `xml:"-" json:"ok,omitempty"`
+	EditStatus *struct {
+		Edit []patchEditStatus `xml:"edit" json:"edit"`
+	} `xml:"edit-status,omitempty" json:"edit-status,omitempty"`
+}
+
+func (s *YangPatchStatus) status() *patchStatus {
+	out := &patchStatus{PatchId: s.PatchId}
+	if s.Ok() {
+		out.Ok = &struct{}{}
+		out.JSONOk = []interface{}{nil}
+		return out
+	}
+	out.EditStatus = &struct {
+		Edit []patchEditStatus `xml:"edit" json:"edit"`
+	}{}
+	for _, e := range s.Edits {
+		if e.Error == nil {
+			continue
+		}
+		out.EditStatus.Edit = append(out.EditStatus.Edit, patchEditStatus{
+			EditId: e.EditId,
+			Errors: &patchErrors{
+				Error: []patchError{newPatchErrorReport(e.Error)},
+			},
+		})
+	}
+	return out
+}
+
+// ToRFC7951 encodes the status as an ietf-yang-patch:yang-patch-status
+func (s *YangPatchStatus) ToRFC7951() []byte {
+	buf, _ := json.Marshal(map[string]*patchStatus{
+		"ietf-yang-patch:yang-patch-status": s.status(),
+	})
+	return buf
+}
+
+func (s *YangPatchStatus) ToXML() []byte {
+	buf, _ := xml.Marshal(s.status())
+	return buf
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2021, AT&T Intellectual Property. All rights reserved.
+//
+// SPDX-License-Identifier: MPL-2.0
+
+package union
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/danos/config/data"
+	"github.com/danos/config/schema"
+)
+
+const yangPatchSchema = `
+	container top {
+		leaf name {
+			type string;
+		}
+		leaf-list tags {
+			type string;
+			ordered-by user;
+		}
+		list entry {
+			key id;
+			ordered-by user;
+			leaf id {
+				type string;
+			}
+			leaf value {
+				type string;
+			}
+		}
+	}`
+
+func newYangPatchTestTree(t *testing.T) Node {
+	sch := newTestSchema(t, yangPatchSchema)
+	root := NewNode(data.New("root"), data.New("root"), sch, nil, 0)
+	for _, path := range [][]string{
+		{"top", "name", "orig"},
+		{"top", "tags", "a"},
+		{"top", "tags", "b"},
+		{"top", "entry", "one", "value", "1"},
+	} {
+		mustSet(t, root, path...)
+	}
+	return root
+}
+
+func applyJSONPatch(t *testing.T, root Node, edits string) *YangPatchStatus {
+	t.Helper()
+	input := `{"ietf-yang-patch:yang-patch":{"patch-id":"p1","edit":[` +
+		edits + `]}}`
+	patch, err := ParseYangPatchJSON(root.GetSchema().(schema.ModelSet),
+		[]byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	return root.ApplyYangPatch(nil, patch)
+}
+
+func TestYangPatchJSON(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	status := applyJSONPatch(t, root,
+		`{"edit-id":"1","operation":"merge","target":"/test-union:top/name",`+
+			`"value":{"test-union:name":"new"}},`+
+			`{"edit-id":"2","operation":"insert","target":"/top/entry=two",`+
+			`"point":"/top/entry=one","where":"before",`+
+			`"value":{"test-union:entry":[{"id":"two","value":"2"}]}},`+
+			`{"edit-id":"3","operation":"move","target":"/top/tags=b",`+
+			`"where":"first"},`+
+			`{"edit-id":"4","operation":"replace","target":"/top/entry=one",`+
+			`"value":{"test-union:entry":[{"id":"one"}]}}`)
+	if !status.Ok() {
+		t.Fatalf("Unexpected failure: %s", status.ToRFC7951())
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"two","value":"2"},`+
+		`{"id":"one"}],"name":"new","tags":["b","a"]}}`)
+
+	expected := `{"ietf-yang-patch:yang-patch-status":{"patch-id":"p1","ok":[null]}}`
+	if actual := string(status.ToRFC7951()); actual != expected {
+		t.Errorf("Unexpected status\n   expect=%s\n   actual=%s",
+			expected, actual)
+	}
+}
+
+func TestYangPatchAtomic(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	status := applyJSONPatch(t, root,
+		`{"edit-id":"1","operation":"delete","target":"/top/name"},`+
+			`{"edit-id":"2","operation":"create","target":"/top/tags=a",`+
+			`"value":{"test-union:tags":["a"]}},`+
+			`{"edit-id":"3","operation":"remove","target":"/top/entry=one"}`)
+	if status.Ok() {
+		t.Fatal("Create of existing node succeeded")
+	}
+	if len(status.Edits) != 2 || status.Edits[1].EditId != "2" {
+		t.Fatalf("Unexpected edit status: %v", status.Edits)
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"}],`+
+		`"name":"orig","tags":["a","b"]}}`)
+
+	out := string(status.ToXML())
+	if !strings.Contains(out, "<edit-id>2</edit-id>") ||
+		!strings.Contains(out, "<error-tag>data-exists</error-tag>") {
+		t.Errorf("Unexpected status: %s", out)
+	}
+}
+
+func TestYangPatchXML(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	input := `<yang-patch xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch">` +
+		`<patch-id>p2</patch-id>` +
+		`<edit><edit-id>1</edit-id><operation>create</operation>` +
+		`<target>/top/entry=two</target>` +
+		`<value><entry xmlns="urn:vyatta.com:test:union">` +
+		`<id>two</id><value>2</value></entry></value></edit>` +
+		`<edit><edit-id>2</edit-id><operation>remove</operation>` +
+		`<target>/top/tags=a</target></edit>` +
+		`</yang-patch>`
+	patch, err := ParseYangPatchXML(root.GetSchema().(schema.ModelSet),
+		[]byte(input))
+	if err != nil {
+		t.Fatalf("Unexpected parse error: %s", err)
+	}
+	if status := root.ApplyYangPatch(nil, patch); !status.Ok() {
+		t.Fatalf("Unexpected failure: %s", status.ToXML())
+	}
+	checkEditConfigResult(t, root, `{"top":{"entry":[{"id":"one","value":"1"},`+
+		`{"id":"two","value":"2"}],"name":"orig","tags":["b"]}}`)
+}
+
+func TestYangPatchBadTarget(t *testing.T) {
+	root := newYangPatchTestTree(t)
+	ms := root.GetSchema().(schema.ModelSet)
+	for _, edit := range []string{
+		`{"edit-id":"1","operation":"remove","target":"/top/missing"}`,
+		`{"edit-id":"1","operation":"remove","target":"/top/entry"}`,
+		`{"edit-id":"1","operation":"frob","target":"/top/name"}`,
+		`{"edit-id":"1","operation":"create","target":"/top/entry=two",` +
+			`"value":{"test-union:entry":[{"id":"three"}]}}`,
+		`{"edit-id":"1","operation":"merge","target":"/",` +
+			`"value":{"test-union:top":{"name":"new"}}}`,
+		`{"edit-id":"1","operation":"create",` +
+			`"value":{"test-union:top":{"name":"new"}}}`,
+	} {
+		input := `{"ietf-yang-patch:yang-patch":{"patch-id":"p","edit":[` +
+			edit + `]}}`
+		if _, err := ParseYangPatchJSON(ms, []byte(input)); err == nil {
+			t.Errorf("Invalid edit accepted: %s", edit)
+		}
+	}
+}
+
+func TestYangPatchRootTarget(t *testing.T) {
+	ms := newYangPatchTestTree(t).GetSchema().(schema.ModelSet)
+	json := `{"ietf-yang-patch:yang-patch":{"patch-id":"p","edit":[` +
+		`{"edit-id":"1","operation":"delete","target":"/"}]}}`
+	xml := `<yang-patch xmlns="urn:ietf:params:xml:ns:yang:ietf-yang-patch">` +
+		`<patch-id>p</patch-id>` +
+		`<edit><edit-id>1</edit-id><operation>merge</operation>` +
+		`<target>/</target>` +
+		`<value><top xmlns="urn:vyatta.com:test:union">` +
+		`<name>new</name></top></value></edit>` +
+		`</yang-patch>`
+	_, jsonErr := ParseYangPatchJSON(ms, []byte(json))
+	_, xmlErr := ParseYangPatchXML(ms, []byte(xml))
+	for _, err := range []error{jsonErr, xmlErr} {
+		if err == nil || !strings.Contains(err.Error(), "invalid target") {
+			t.Errorf("Expected invalid target error, got %v", err)
+		}
+	}
+}